@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+
+	"whatsdot-aibuddy/backend/internal/config"
+	"whatsdot-aibuddy/backend/internal/httpapi"
+	"whatsdot-aibuddy/backend/internal/janitor"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+func main() {
+	cfg := config.Load()
+
+	ctx := context.Background()
+	db, err := store.Connect(ctx, cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("connect db failed: %v", err)
+	}
+	defer db.Close()
+
+	j := &janitor.Janitor{
+		Store:       &store.Store{DB: db},
+		UploadDir:   cfg.UploadDir,
+		Grace:       cfg.UploadOrphanGrace,
+		Retention:   cfg.UploadRetention,
+		PartialDir:  filepath.Join(cfg.UploadDir, httpapi.PartialUploadDir),
+		CacheDir:    filepath.Join(cfg.UploadDir, httpapi.SpeechCacheDir),
+		CacheMaxAge: cfg.TTSCacheMaxAge,
+	}
+	rep, err := j.RunOnce(ctx)
+	if err != nil {
+		log.Fatalf("janitor failed: %v", err)
+	}
+	log.Printf("janitor ok: expired_records=%d expired_sessions=%d scanned=%d deleted=%d cache_pruned=%d freed_bytes=%d",
+		rep.ExpiredRecords, rep.ExpiredSessions, rep.Scanned, rep.Deleted, rep.CachePruned, rep.FreedBytes)
+}
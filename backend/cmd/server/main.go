@@ -6,14 +6,20 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"whatsdot-aibuddy/backend/internal/config"
+	"whatsdot-aibuddy/backend/internal/curriculum"
 	"whatsdot-aibuddy/backend/internal/httpapi"
+	"whatsdot-aibuddy/backend/internal/janitor"
 	"whatsdot-aibuddy/backend/internal/logger"
+	"whatsdot-aibuddy/backend/internal/media"
 	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/safety"
 	"whatsdot-aibuddy/backend/internal/store"
+	"whatsdot-aibuddy/backend/internal/taxonomy"
 )
 
 func main() {
@@ -32,12 +38,70 @@ func main() {
 	}
 	defer db.Close()
 
+	catalog, err := curriculum.Default()
+	if cfg.CurriculumDir != "" {
+		catalog, err = curriculum.Load(os.DirFS(cfg.CurriculumDir))
+	}
+	if err != nil {
+		log.Fatalf("load curriculum failed: %v", err)
+	}
+
+	st := &store.Store{DB: db}
+	tax := &taxonomy.Taxonomy{Store: st}
+	if err := tax.Reload(ctx); err != nil {
+		log.Printf("load knowledge taxonomy failed, knowledge points stay unnormalized: %v", err)
+	}
+	oa := openai.New(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, cfg.OpenAIModel)
+	oa.Knowledge = tax
+
+	rules, err := safety.Default()
+	if cfg.SafetyRulesFile != "" {
+		rules, err = safety.Load(os.DirFS(filepath.Dir(cfg.SafetyRulesFile)), filepath.Base(cfg.SafetyRulesFile))
+	}
+	if err != nil {
+		log.Fatalf("load safety rules failed: %v", err)
+	}
+	filter := &safety.Filter{Rules: rules}
+	if cfg.SafetyModeration {
+		filter.Moderator = oa
+	}
+	oa.Safety = filter
+	oa.SafetyRegenerate = cfg.SafetyRegenerate
+
 	svc := &httpapi.Server{
-		Store:       &store.Store{DB: db},
-		OpenAI:      openai.New(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, cfg.OpenAIModel),
+		Store:       st,
+		OpenAI:      oa,
+		Speaker:     openai.NewSpeaker(cfg.TTSBaseURL, cfg.TTSAPIKey, cfg.TTSModel, cfg.TTSVoice),
+		Transcriber: openai.NewTranscriber(cfg.STTBaseURL, cfg.STTAPIKey, cfg.STTModel),
 		UploadDir:   cfg.UploadDir,
 		AnalyzeMock: cfg.AnalyzeMock,
 		Limiter:     httpapi.NewDeviceLimiter(cfg.RateLimitCapacity, cfg.RateLimitRefill),
+		Curriculum:  catalog,
+		Taxonomy:    tax,
+		AdminToken:  cfg.AdminToken,
+
+		ReportNarrative: cfg.ReportNarrative,
+		AudioMaxBytes:   cfg.AudioMaxBytes,
+		UploadLimits: media.Limits{
+			MaxBytes:  cfg.UploadMaxBytes,
+			MaxSide:   cfg.UploadMaxSide,
+			MaxPixels: cfg.UploadMaxPixels,
+		},
+	}
+
+	janitorCtx, stopJanitor := context.WithCancel(ctx)
+	defer stopJanitor()
+	if cfg.JanitorInterval > 0 {
+		j := &janitor.Janitor{
+			Store:       st,
+			UploadDir:   cfg.UploadDir,
+			Grace:       cfg.UploadOrphanGrace,
+			Retention:   cfg.UploadRetention,
+			PartialDir:  filepath.Join(cfg.UploadDir, httpapi.PartialUploadDir),
+			CacheDir:    filepath.Join(cfg.UploadDir, httpapi.SpeechCacheDir),
+			CacheMaxAge: cfg.TTSCacheMaxAge,
+		}
+		go j.Run(janitorCtx, cfg.JanitorInterval)
 	}
 
 	httpSrv := &http.Server{
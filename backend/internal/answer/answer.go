@@ -0,0 +1,137 @@
+package answer
+
+import (
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Normalize folds full-width characters, drops whitespace and trailing sentence
+// punctuation, and lower-cases the text so typed answers compare loosely.
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r == '　':
+			continue
+		case r >= '！' && r <= '～':
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return strings.TrimRight(b.String(), "。.!！")
+}
+
+// Equal reports whether a child's answer matches the expected one, comparing
+// numerically when both start with a number ("360" equals "360个" and "0.5" equals
+// "1/2"). Text after the given number must be a unit, not more arithmetic.
+func Equal(expected, given string) bool {
+	e, g := Normalize(expected), Normalize(given)
+	if e == "" || g == "" {
+		return false
+	}
+	if e == g {
+		return true
+	}
+	en, eunit, ok1 := ParseNumber(e)
+	gn, gunit, ok2 := ParseNumber(g)
+	if !ok1 || !ok2 {
+		return false
+	}
+	if hasOperand(eunit) || !isUnit(gunit, eunit) {
+		return false
+	}
+	if eunit != "" && gunit != "" && eunit != gunit {
+		return false
+	}
+	return en.Cmp(gn) == 0
+}
+
+// units are the unit and measure words accepted after a number when the expected
+// answer does not name its own.
+var units = map[string]bool{
+	"个": true, "只": true, "本": true, "支": true, "张": true, "条": true, "件": true, "块": true,
+	"颗": true, "棵": true, "朵": true, "根": true, "位": true, "名": true, "人": true, "辆": true,
+	"箱": true, "盒": true, "袋": true, "瓶": true, "杯": true, "包": true, "把": true, "双": true,
+	"次": true, "页": true, "道": true, "岁": true, "倍": true, "份": true, "组": true, "排": true,
+	"元": true, "角": true, "分": true, "天": true, "时": true, "小时": true, "分钟": true, "秒": true,
+	"毫米": true, "厘米": true, "分米": true, "米": true, "千米": true, "公里": true,
+	"平方厘米": true, "平方分米": true, "平方米": true, "平方千米": true, "公顷": true,
+	"立方厘米": true, "立方分米": true, "立方米": true, "毫升": true, "升": true,
+	"克": true, "千克": true, "公斤": true, "斤": true, "吨": true, "度": true, "°": true,
+	"mm": true, "cm": true, "dm": true, "m": true, "km": true, "g": true, "kg": true, "ml": true, "l": true,
+}
+
+// isUnit reports whether the text after a given number is only a unit: empty,
+// the expected answer's own unit or a known unit word. It keeps "12×3" or
+// "360或者400" from passing as 12 and 360.
+func isUnit(unit, expectedUnit string) bool {
+	if unit == "" {
+		return true
+	}
+	return !hasOperand(unit) && (unit == expectedUnit || units[unit])
+}
+
+// hasOperand reports whether text contains digits or arithmetic operators.
+func hasOperand(s string) bool {
+	return strings.ContainsAny(s, "0123456789+-×*÷/=<>.")
+}
+
+// ParseNumber reads a leading integer, decimal, fraction ("3/4") or mixed number
+// ("1又1/2") from normalized text and returns it with the remaining unit text.
+func ParseNumber(s string) (*big.Rat, string, bool) {
+	s = strings.ReplaceAll(s, ",", "")
+	whole, rest := splitNumber(s)
+	if whole == "" {
+		return nil, s, false
+	}
+	n, ok := new(big.Rat).SetString(whole)
+	if !ok {
+		return nil, s, false
+	}
+	if strings.HasPrefix(rest, "又") {
+		frac, tail := splitNumber(strings.TrimPrefix(rest, "又"))
+		f, ok := new(big.Rat).SetString(frac)
+		if frac == "" || !ok || !strings.Contains(frac, "/") {
+			return nil, s, false
+		}
+		if n.Sign() < 0 {
+			f.Neg(f)
+		}
+		n.Add(n, f)
+		rest = tail
+	}
+	return n, rest, true
+}
+
+// splitNumber splits a leading numeric literal (sign, digits, one '.' or one '/')
+// from the rest of s.
+func splitNumber(s string) (string, string) {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := 0
+	dot, slash := false, false
+scan:
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot && !slash && digits > 0:
+			dot = true
+		case c == '/' && !dot && !slash && digits > 0:
+			slash = true
+		default:
+			break scan
+		}
+	}
+	num := strings.TrimRight(s[:i], "./")
+	if digits == 0 {
+		return "", s
+	}
+	return num, s[len(num):]
+}
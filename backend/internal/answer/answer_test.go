@@ -0,0 +1,45 @@
+package answer
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		expected, given string
+		want            bool
+	}{
+		{"360", "360", true},
+		{"360", " ３６０ ", true},
+		{"360", "360个", true},
+		{"360个", "360", true},
+		{"360个", "360米", false},
+		{"0.5", "1/2", true},
+		{"1又1/2", "1.5", true},
+		{"3/4", "6/8", true},
+		{"1,200", "1200", true},
+		{"359", "360", false},
+		{"Apple", "apple.", true},
+		{"分配律", "乘法分配律", false},
+		{"360", "", false},
+		{"360", "360只", true},
+		{"360", "360个苹果", false},
+		{"12", "12×3", false},
+		{"360", "360+5", false},
+		{"360", "360或者400", false},
+		{"7余6", "7", false},
+	}
+	for _, tc := range cases {
+		if got := Equal(tc.expected, tc.given); got != tc.want {
+			t.Errorf("Equal(%q, %q) = %v, want %v", tc.expected, tc.given, got, tc.want)
+		}
+	}
+}
+
+func TestParseNumberKeepsUnit(t *testing.T) {
+	n, unit, ok := ParseNumber("12.5厘米")
+	if !ok || n.FloatString(1) != "12.5" || unit != "厘米" {
+		t.Fatalf("unexpected parse: %v %q %v", n, unit, ok)
+	}
+	if _, _, ok := ParseNumber("约等于"); ok {
+		t.Fatalf("expected no number")
+	}
+}
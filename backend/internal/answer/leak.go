@@ -0,0 +1,213 @@
+package answer
+
+import (
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// Variants lists the ways an answer may be written in text: the answer as given
+// and, for numbers, its decimal, fraction and Chinese numeral forms ("360" →
+// "三百六十", "0.5" → "1/2", "二分之一", "零点五"). A one-character Chinese numeral
+// is left out: 一 or 十 appears in too many ordinary words ("一起", "第一步").
+func Variants(ans string) []string {
+	ans = strings.TrimSpace(ans)
+	n, _, ok := ParseNumber(Normalize(ans))
+	if !ok {
+		if utf8.RuneCountInString(ans) < 2 {
+			return nil
+		}
+		return []string{ans}
+	}
+
+	var out []string
+	add := func(v string) {
+		if v == "" || utf8.RuneCountInString(v) == 1 && isChineseDigit([]rune(v)[0]) {
+			return
+		}
+		for _, o := range out {
+			if o == v {
+				return
+			}
+		}
+		out = append(out, v)
+	}
+	if n.IsInt() {
+		add(n.Num().String())
+		add(chineseInt(n.Num()))
+		return out
+	}
+	add(n.RatString())
+	if dec, ok := finiteDecimal(n); ok {
+		add(dec)
+		add(chineseDecimal(dec))
+	}
+	if n.Sign() > 0 {
+		add(chineseInt(n.Denom()) + "分之" + chineseInt(n.Num()))
+	}
+	return out
+}
+
+// FindLeak reports whether text contains one of the variants as a whole number or
+// word, so "360" is found in "等于360个" but not in "3600".
+func FindLeak(text string, variants []string) bool {
+	for _, v := range variants {
+		if len(findAll(text, v)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact replaces every whole occurrence of the variants in text with mask.
+func Redact(text string, variants []string, mask string) string {
+	for _, v := range variants {
+		spans := findAll(text, v)
+		for i := len(spans) - 1; i >= 0; i-- {
+			text = text[:spans[i]] + mask + text[spans[i]+len(v):]
+		}
+	}
+	return text
+}
+
+func findAll(text, v string) []int {
+	if v == "" {
+		return nil
+	}
+	var spans []int
+	for i := 0; ; {
+		j := strings.Index(text[i:], v)
+		if j < 0 {
+			return spans
+		}
+		start := i + j
+		end := start + len(v)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		first, _ := utf8.DecodeRuneInString(v)
+		last, _ := utf8.DecodeLastRuneInString(v)
+		if !(joins(before, first) || joins(last, after)) {
+			spans = append(spans, start)
+		}
+		i = start + len(v)
+	}
+}
+
+// joins reports whether two adjacent runes belong to the same number.
+func joins(a, b rune) bool {
+	numeric := func(r rune) bool { return r >= '0' && r <= '9' || r == '.' }
+	if numeric(a) && numeric(b) {
+		return true
+	}
+	return isChineseDigit(a) && isChineseDigit(b)
+}
+
+func isChineseDigit(r rune) bool {
+	return strings.ContainsRune("零〇一二两三四五六七八九十百千万亿点", r)
+}
+
+var chineseDigits = []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+
+// chineseInt writes a non-negative integer below 10^16 in Chinese numerals, e.g.
+// 105 → 一百零五, 15 → 十五, 20300 → 二万零三百. Larger values return "".
+func chineseInt(n *big.Int) string {
+	if n.Sign() < 0 {
+		s := chineseInt(new(big.Int).Neg(n))
+		if s == "" {
+			return ""
+		}
+		return "负" + s
+	}
+	if !n.IsInt64() || n.Int64() >= 1e16 {
+		return ""
+	}
+	v := n.Int64()
+	if v == 0 {
+		return "零"
+	}
+	var b strings.Builder
+	groups := []struct {
+		unit string
+		size int64
+	}{{"亿", 1e8}, {"万", 1e4}, {"", 1}}
+	zero := false
+	for _, g := range groups {
+		part := v / g.size % 1e4
+		if g.unit == "亿" {
+			part = v / g.size
+		}
+		if part == 0 {
+			if b.Len() > 0 {
+				zero = true
+			}
+			continue
+		}
+		if zero || (b.Len() > 0 && part < 1000) {
+			b.WriteString("零")
+		}
+		zero = false
+		b.WriteString(chineseSection(part))
+		b.WriteString(g.unit)
+	}
+	s := b.String()
+	// 一十五 is written 十五 at the start of a number.
+	if strings.HasPrefix(s, "一十") {
+		s = strings.TrimPrefix(s, "一")
+	}
+	return s
+}
+
+// chineseSection writes 1..9999 with 千百十 units and inner zeros.
+func chineseSection(v int64) string {
+	units := []string{"千", "百", "十", ""}
+	divs := []int64{1000, 100, 10, 1}
+	var b strings.Builder
+	zero := false
+	for i, d := range divs {
+		digit := v / d % 10
+		if digit == 0 {
+			if b.Len() > 0 {
+				zero = true
+			}
+			continue
+		}
+		if zero {
+			b.WriteString("零")
+			zero = false
+		}
+		b.WriteString(chineseDigits[digit])
+		b.WriteString(units[i])
+	}
+	return b.String()
+}
+
+// finiteDecimal renders n as a decimal when it has a short exact expansion.
+func finiteDecimal(n *big.Rat) (string, bool) {
+	for prec := 1; prec <= 6; prec++ {
+		s := n.FloatString(prec)
+		if r, ok := new(big.Rat).SetString(s); ok && r.Cmp(n) == 0 {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func chineseDecimal(dec string) string {
+	whole, frac, _ := strings.Cut(dec, ".")
+	w, ok := new(big.Int).SetString(whole, 10)
+	if !ok {
+		return ""
+	}
+	s := chineseInt(w)
+	if s == "" {
+		return ""
+	}
+	if whole == "-0" {
+		s = "负零"
+	}
+	s += "点"
+	for _, c := range frac {
+		s += chineseDigits[c-'0']
+	}
+	return s
+}
@@ -0,0 +1,60 @@
+package answer
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestChineseInt(t *testing.T) {
+	cases := map[int64]string{
+		0: "零", 7: "七", 10: "十", 15: "十五", 20: "二十", 105: "一百零五", 360: "三百六十",
+		1001: "一千零一", 10000: "一万", 10050: "一万零五十", 20300: "二万零三百", 110000: "十一万",
+		100000000: "一亿", 100000001: "一亿零一",
+	}
+	for n, want := range cases {
+		if got := chineseInt(big.NewInt(n)); got != want {
+			t.Fatalf("chineseInt(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	cases := map[string][]string{
+		"360个":  {"360", "三百六十"},
+		"0.5":   {"1/2", "0.5", "零点五", "二分之一"},
+		"3/4":   {"3/4", "0.75", "零点七五", "四分之三"},
+		"A":     nil,
+		"1":     {"1"},
+		"10":    {"10"},
+		"12":    {"12", "十二"},
+		"春眠不觉晓": {"春眠不觉晓"},
+	}
+	for in, want := range cases {
+		if got := Variants(in); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Variants(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestFindLeakAndRedact(t *testing.T) {
+	v := Variants("360")
+	if !FindLeak("所以一共是360个。", v) || !FindLeak("答案是三百六十", v) {
+		t.Fatalf("expected leak to be found")
+	}
+	if FindLeak("3600 和 1360 都不是", v) || FindLeak("三百六十五天", v) {
+		t.Fatalf("numbers containing the answer are not leaks")
+	}
+	if got := Redact("240+120=360，也就是三百六十", v, "□"); got != "240+120=□，也就是□" {
+		t.Fatalf("unexpected redaction: %q", got)
+	}
+
+	// Small answers must not be found inside ordinary words.
+	one := Variants("1")
+	if got := Redact("我们一起想一想，第一步", one, "□"); got != "我们一起想一想，第一步" {
+		t.Fatalf("ordinary words redacted: %q", got)
+	}
+	if got := Redact("所以答案是1", one, "□"); got != "所以答案是□" {
+		t.Fatalf("unexpected redaction: %q", got)
+	}
+}
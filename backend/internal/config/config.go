@@ -20,11 +20,40 @@ type Config struct {
 	LogDir         string
 	UploadDir      string
 
+	UploadMaxBytes  int64
+	UploadMaxSide   int
+	UploadMaxPixels int64
+
+	UploadOrphanGrace time.Duration
+	UploadRetention   time.Duration
+	JanitorInterval   time.Duration
+
 	OpenAIBaseURL string
 	OpenAIAPIKey  string
 	OpenAIModel   string
 	AnalyzeMock   bool
 
+	TTSBaseURL string
+	TTSAPIKey  string
+	TTSModel   string
+	TTSVoice   string
+	// TTSCacheMaxAge is how long unused cached speech audio is kept.
+	TTSCacheMaxAge time.Duration
+
+	STTBaseURL    string
+	STTAPIKey     string
+	STTModel      string
+	AudioMaxBytes int64
+
+	CurriculumDir string
+	AdminToken    string
+
+	ReportNarrative bool
+
+	SafetyRulesFile  string
+	SafetyModeration bool
+	SafetyRegenerate bool
+
 	RateLimitCapacity int
 	RateLimitRefill   int
 }
@@ -44,14 +73,54 @@ func Load() Config {
 		LogDir:         getEnv("LOG_DIR", "logs"),
 		UploadDir:      getEnv("UPLOAD_DIR", "uploads"),
 
+		UploadMaxBytes:  int64(getEnvInt("UPLOAD_MAX_MB", 8)) << 20,
+		UploadMaxSide:   getEnvInt("UPLOAD_MAX_SIDE", 10000),
+		UploadMaxPixels: int64(getEnvInt("UPLOAD_MAX_MEGAPIXELS", 25)) * 1_000_000,
+
+		UploadOrphanGrace: time.Duration(getEnvInt("UPLOAD_ORPHAN_GRACE_HOURS", 24)) * time.Hour,
+		UploadRetention:   time.Duration(getEnvInt("UPLOAD_RETENTION_DAYS", 0)) * 24 * time.Hour,
+		JanitorInterval:   time.Duration(getEnvInt("JANITOR_INTERVAL_MINUTES", 60)) * time.Minute,
+
 		OpenAIBaseURL: getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
 		OpenAIAPIKey:  os.Getenv("OPENAI_API_KEY"),
 		OpenAIModel:   getEnv("OPENAI_MODEL", "gpt-4o-mini"),
 		AnalyzeMock:   getEnvBool("ANALYZE_MOCK", false),
 
+		TTSBaseURL:     os.Getenv("TTS_BASE_URL"),
+		TTSAPIKey:      os.Getenv("TTS_API_KEY"),
+		TTSModel:       getEnv("TTS_MODEL", "gpt-4o-mini-tts"),
+		TTSVoice:       getEnv("TTS_VOICE", "nova"),
+		TTSCacheMaxAge: time.Duration(getEnvInt("TTS_CACHE_DAYS", 30)) * 24 * time.Hour,
+
+		STTBaseURL:    os.Getenv("STT_BASE_URL"),
+		STTAPIKey:     os.Getenv("STT_API_KEY"),
+		STTModel:      getEnv("STT_MODEL", "gpt-4o-mini-transcribe"),
+		AudioMaxBytes: int64(getEnvInt("AUDIO_MAX_MB", 10)) << 20,
+
+		CurriculumDir: os.Getenv("CURRICULUM_DIR"),
+		AdminToken:    os.Getenv("ADMIN_TOKEN"),
+
+		ReportNarrative: getEnvBool("REPORT_NARRATIVE", true),
+
+		SafetyRulesFile:  os.Getenv("SAFETY_RULES_FILE"),
+		SafetyModeration: getEnvBool("SAFETY_MODERATION", false),
+		SafetyRegenerate: getEnvBool("SAFETY_REGENERATE", true),
+
 		RateLimitCapacity: getEnvInt("RATE_LIMIT_CAPACITY", 6),
 		RateLimitRefill:   getEnvInt("RATE_LIMIT_REFILL_PER_MIN", 6),
 	}
+	if cfg.TTSBaseURL == "" {
+		cfg.TTSBaseURL = cfg.OpenAIBaseURL
+	}
+	if cfg.TTSAPIKey == "" {
+		cfg.TTSAPIKey = cfg.OpenAIAPIKey
+	}
+	if cfg.STTBaseURL == "" {
+		cfg.STTBaseURL = cfg.OpenAIBaseURL
+	}
+	if cfg.STTAPIKey == "" {
+		cfg.STTAPIKey = cfg.OpenAIAPIKey
+	}
 	return cfg
 }
 
@@ -0,0 +1,145 @@
+// Package curriculum holds the knowledge point names used by each textbook edition,
+// per subject and grade, so analyses can name points the way the school does.
+package curriculum
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed data/*.json
+var embedded embed.FS
+
+// file is the layout of one data file: the knowledge points of one edition, keyed by
+// subject and then grade. A shared edition (e.g. the nationally unified 统编版 for
+// Chinese) applies whatever edition the child uses.
+type file struct {
+	Edition  string                         `json:"edition"`
+	Aliases  []string                       `json:"aliases"`
+	Shared   bool                           `json:"shared"`
+	Subjects map[string]map[string][]string `json:"subjects"`
+}
+
+type Catalog struct {
+	editions map[string]file
+	aliases  map[string]string
+	shared   []string
+}
+
+// Default loads the data files compiled into the binary.
+func Default() (*Catalog, error) {
+	return Load(embedded)
+}
+
+// Load reads every *.json under data/ in fsys, or at its root if there is no data/.
+func Load(fsys fs.FS) (*Catalog, error) {
+	names, err := fs.Glob(fsys, "data/*.json")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		if names, err = fs.Glob(fsys, "*.json"); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(names)
+
+	c := &Catalog{editions: map[string]file{}, aliases: map[string]string{}}
+	for _, name := range names {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var f file
+		if err := json.Unmarshal(b, &f); err != nil {
+			return nil, fmt.Errorf("curriculum %s: %w", path.Base(name), err)
+		}
+		f.Edition = strings.TrimSpace(f.Edition)
+		if f.Edition == "" {
+			return nil, fmt.Errorf("curriculum %s: edition missing", path.Base(name))
+		}
+		c.editions[f.Edition] = f
+		c.aliases[aliasKey(f.Edition)] = f.Edition
+		for _, a := range f.Aliases {
+			c.aliases[aliasKey(a)] = f.Edition
+		}
+		if f.Shared {
+			c.shared = append(c.shared, f.Edition)
+		}
+	}
+	return c, nil
+}
+
+// Edition maps a user-entered edition name ("人教", "bsd") to its canonical name,
+// or "" when it is unknown.
+func (c *Catalog) Edition(name string) string {
+	if c == nil {
+		return ""
+	}
+	return c.aliases[aliasKey(name)]
+}
+
+// Editions lists the canonical edition names.
+func (c *Catalog) Editions() []string {
+	if c == nil {
+		return nil
+	}
+	out := make([]string, 0, len(c.editions))
+	for name := range c.editions {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Points returns the knowledge point names of an edition and grade, including the
+// shared editions. An empty subject returns every subject.
+func (c *Catalog) Points(edition, grade, subject string) []string {
+	if c == nil {
+		return nil
+	}
+	grade = strings.TrimSpace(grade)
+	if grade == "" {
+		return nil
+	}
+	var editions []string
+	if e := c.Edition(edition); e != "" {
+		editions = append(editions, e)
+	}
+	for _, e := range c.shared {
+		if len(editions) == 0 || editions[0] != e {
+			editions = append(editions, e)
+		}
+	}
+
+	seen := map[string]bool{}
+	var out []string
+	for _, e := range editions {
+		f := c.editions[e]
+		subjects := make([]string, 0, len(f.Subjects))
+		for s := range f.Subjects {
+			if subject == "" || s == subject {
+				subjects = append(subjects, s)
+			}
+		}
+		sort.Strings(subjects)
+		for _, s := range subjects {
+			for _, p := range f.Subjects[s][grade] {
+				if !seen[p] {
+					seen[p] = true
+					out = append(out, p)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func aliasKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
@@ -0,0 +1,50 @@
+package curriculum
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestDefaultResolvesAliasesAndPoints(t *testing.T) {
+	c, err := Default()
+	if err != nil {
+		t.Fatalf("load default: %v", err)
+	}
+	if got := c.Edition(" 人教 "); got != "人教版" {
+		t.Fatalf("expected alias to resolve to 人教版, got %q", got)
+	}
+	if got := c.Edition("BSD"); got != "北师大版" {
+		t.Fatalf("expected alias to resolve to 北师大版, got %q", got)
+	}
+	if got := c.Edition("沪教版"); got != "" {
+		t.Fatalf("expected unknown edition, got %q", got)
+	}
+
+	math := c.Points("人教版", "四年级", "math")
+	if !contains(math, "乘法分配律") || contains(math, "关联词") {
+		t.Fatalf("unexpected math points: %v", math)
+	}
+	all := c.Points("北师大", "四年级", "")
+	if !contains(all, "运算律") || !contains(all, "关联词") {
+		t.Fatalf("expected edition and shared chinese points, got %v", all)
+	}
+	if got := c.Points("人教版", "", ""); len(got) != 0 {
+		t.Fatalf("expected no points without grade, got %v", got)
+	}
+}
+
+func TestLoadRejectsFileWithoutEdition(t *testing.T) {
+	_, err := Load(fstest.MapFS{"x.json": {Data: []byte(`{"subjects":{}}`)}})
+	if err == nil {
+		t.Fatalf("expected error for missing edition")
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+// Package expr evaluates plain arithmetic expressions exactly, so the server can
+// check the arithmetic of a model-produced answer without running any code.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Limits keep hostile input cheap to reject.
+const (
+	maxLength = 256
+	maxDepth  = 32
+)
+
+var (
+	ErrSyntax         = errors.New("invalid expression")
+	ErrDivisionByZero = errors.New("division by zero")
+	ErrTooComplex     = errors.New("expression too complex")
+)
+
+// Eval computes an expression of numbers (integers or decimals), + - * / and
+// parentheses. The usual Chinese and full-width forms (×, ÷, （）, －) are accepted.
+// Results are exact rationals. Only the left side of an equation is evaluated, so
+// "24×15=360" is 24×15.
+func Eval(s string) (*big.Rat, error) {
+	if i := strings.IndexAny(s, "=＝"); i >= 0 {
+		s = s[:i]
+	}
+	s = replacer.Replace(strings.TrimSpace(s))
+	if s == "" {
+		return nil, ErrSyntax
+	}
+	if len(s) > maxLength {
+		return nil, ErrTooComplex
+	}
+	p := &parser{src: s}
+	v, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.src) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, p.src[p.pos:])
+	}
+	return v, nil
+}
+
+var replacer = strings.NewReplacer(
+	"×", "*", "÷", "/", "（", "(", "）", ")", "－", "-", "＋", "+",
+	"＊", "*", "／", "/", "·", "*", "x", "*", "X", "*",
+)
+
+type parser struct {
+	src string
+	pos int
+}
+
+// expr := term (('+' | '-') term)*
+func (p *parser) expr(depth int) (*big.Rat, error) {
+	if depth > maxDepth {
+		return nil, ErrTooComplex
+	}
+	v, err := p.term(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return v, nil
+		}
+		p.pos++
+		rhs, err := p.term(depth)
+		if err != nil {
+			return nil, err
+		}
+		if op == '+' {
+			v.Add(v, rhs)
+		} else {
+			v.Sub(v, rhs)
+		}
+	}
+}
+
+// term := factor (('*' | '/') factor)*
+func (p *parser) term(depth int) (*big.Rat, error) {
+	v, err := p.factor(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return v, nil
+		}
+		p.pos++
+		rhs, err := p.factor(depth)
+		if err != nil {
+			return nil, err
+		}
+		if op == '*' {
+			v.Mul(v, rhs)
+		} else {
+			if rhs.Sign() == 0 {
+				return nil, ErrDivisionByZero
+			}
+			v.Quo(v, rhs)
+		}
+	}
+}
+
+// factor := '-' factor | '(' expr ')' | number
+func (p *parser) factor(depth int) (*big.Rat, error) {
+	if depth > maxDepth {
+		return nil, ErrTooComplex
+	}
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '-':
+		p.pos++
+		v, err := p.factor(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return v.Neg(v), nil
+	case c == '(':
+		p.pos++
+		v, err := p.expr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("%w: missing )", ErrSyntax)
+		}
+		p.pos++
+		return v, nil
+	case c >= '0' && c <= '9' || c == '.':
+		return p.number()
+	default:
+		return nil, fmt.Errorf("%w: at %d", ErrSyntax, p.pos)
+	}
+}
+
+func (p *parser) number() (*big.Rat, error) {
+	start := p.pos
+	dot := false
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '.' && !dot {
+			dot = true
+		} else if c < '0' || c > '9' {
+			break
+		}
+		p.pos++
+	}
+	lit := p.src[start:p.pos]
+	if lit == "." {
+		return nil, fmt.Errorf("%w: bad number", ErrSyntax)
+	}
+	v, ok := new(big.Rat).SetString(lit)
+	if !ok {
+		return nil, fmt.Errorf("%w: bad number %q", ErrSyntax, lit)
+	}
+	return v, nil
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
@@ -0,0 +1,50 @@
+package expr
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	cases := map[string]string{
+		"24 × 15":          "360",
+		"24*10+24*5":       "360",
+		"（48 ÷ 6）× 3":      "24",
+		"1/3 + 1/6":        "1/2",
+		"0.1 + 0.2":        "3/10",
+		"-(3 - 5) * 2":     "4",
+		"2 - -3":           "5",
+		"100 - 36 - 28 = ": "36",
+		"24*15=360":        "360",
+		"24×15＝361":        "360",
+	}
+	for in, want := range cases {
+		got, err := Eval(in)
+		if err != nil {
+			t.Fatalf("Eval(%q): %v", in, err)
+		}
+		w, _ := new(big.Rat).SetString(want)
+		if got.Cmp(w) != 0 {
+			t.Fatalf("Eval(%q) = %s, want %s", in, got.RatString(), want)
+		}
+	}
+}
+
+func TestEvalRejects(t *testing.T) {
+	cases := map[string]error{
+		"":                            ErrSyntax,
+		"2 +":                         ErrSyntax,
+		"(1 + 2":                      ErrSyntax,
+		"os.Exit(1)":                  ErrSyntax,
+		"1 / (2 - 2)":                 ErrDivisionByZero,
+		strings.Repeat("(", 40) + "1": ErrTooComplex,
+		strings.Repeat("1+", 200):     ErrTooComplex,
+	}
+	for in, want := range cases {
+		if _, err := Eval(in); !errors.Is(err, want) {
+			t.Fatalf("Eval(%q) error = %v, want %v", in, err, want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+type knowledgePointReq struct {
+	Subject string   `json:"subject"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+type resolveUnknownReq struct {
+	// PointID maps the name as an alias of an existing point; 0 promotes the name
+	// to a new canonical point.
+	PointID int64 `json:"pointId"`
+}
+
+// adminOnly guards the admin routes with ADMIN_TOKEN, sent as a bearer token or
+// X-Admin-Token. Without a configured token the routes do not exist.
+func (s *Server) adminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.AdminToken == "" {
+			s.fail(c, http.StatusNotFound, 40400, "not found")
+			c.Abort()
+			return
+		}
+		token := strings.TrimSpace(c.GetHeader("X-Admin-Token"))
+		if token == "" {
+			token = strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) != 1 {
+			s.fail(c, http.StatusUnauthorized, 40101, "admin token required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *Server) handleAdminKnowledgePoints(c *gin.Context) {
+	points, err := s.Store.ListKnowledgePoints(c.Request.Context())
+	if err != nil {
+		log.Printf("[ERROR] list knowledge points: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50023, "query knowledge points failed")
+		return
+	}
+	s.success(c, gin.H{"items": points})
+}
+
+func (s *Server) handleAdminKnowledgePointCreate(c *gin.Context) {
+	var req knowledgePointReq
+	_ = c.ShouldBindJSON(&req)
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		s.fail(c, http.StatusBadRequest, 40025, "name required")
+		return
+	}
+	id, err := s.Store.CreateKnowledgePoint(c.Request.Context(), openai.NormalizeSubject(req.Subject), name, trimAll(req.Aliases))
+	if err != nil {
+		log.Printf("[ERROR] create knowledge point: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50024, "save knowledge point failed")
+		return
+	}
+	s.reloadTaxonomy(c)
+	s.success(c, gin.H{"id": id})
+}
+
+func (s *Server) handleAdminKnowledgeAliases(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("pid"), 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40026, "invalid knowledge point id")
+		return
+	}
+	var req knowledgePointReq
+	_ = c.ShouldBindJSON(&req)
+	aliases := trimAll(req.Aliases)
+	if len(aliases) == 0 {
+		s.fail(c, http.StatusBadRequest, 40025, "aliases required")
+		return
+	}
+	if err := s.Store.AddKnowledgeAliases(c.Request.Context(), id, aliases); err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40408, "knowledge point not found")
+			return
+		}
+		log.Printf("[ERROR] add knowledge aliases: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50024, "save knowledge point failed")
+		return
+	}
+	s.reloadTaxonomy(c)
+	s.success(c, gin.H{"ok": true})
+}
+
+func (s *Server) handleAdminUnknownKnowledge(c *gin.Context) {
+	status := strings.TrimSpace(c.Query("status"))
+	if status == "" {
+		status = store.UnknownPending
+	}
+	items, err := s.Store.ListUnknownKnowledgePoints(c.Request.Context(), status, 200)
+	if err != nil {
+		log.Printf("[ERROR] list unknown knowledge points: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50023, "query knowledge points failed")
+		return
+	}
+	s.success(c, gin.H{"items": items})
+}
+
+// handleAdminResolveUnknown maps a queued name onto a canonical point, so later
+// analyses producing it are normalized.
+func (s *Server) handleAdminResolveUnknown(c *gin.Context) {
+	u, ok := s.loadUnknown(c)
+	if !ok {
+		return
+	}
+	var req resolveUnknownReq
+	_ = c.ShouldBindJSON(&req)
+
+	ctx := c.Request.Context()
+	pointID := req.PointID
+	var err error
+	if pointID > 0 {
+		err = s.Store.AddKnowledgeAliases(ctx, pointID, []string{u.Name})
+	} else {
+		pointID, err = s.Store.CreateKnowledgePoint(ctx, u.Subject, u.Name, nil)
+	}
+	if err == nil {
+		err = s.Store.SetUnknownKnowledgePointStatus(ctx, u.ID, store.UnknownResolved, &pointID)
+	}
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40408, "knowledge point not found")
+			return
+		}
+		log.Printf("[ERROR] resolve unknown knowledge point: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50024, "save knowledge point failed")
+		return
+	}
+	s.reloadTaxonomy(c)
+	s.success(c, gin.H{"pointId": pointID})
+}
+
+func (s *Server) handleAdminRejectUnknown(c *gin.Context) {
+	u, ok := s.loadUnknown(c)
+	if !ok {
+		return
+	}
+	if err := s.Store.SetUnknownKnowledgePointStatus(c.Request.Context(), u.ID, store.UnknownRejected, nil); err != nil {
+		log.Printf("[ERROR] reject unknown knowledge point: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50024, "save knowledge point failed")
+		return
+	}
+	s.success(c, gin.H{"ok": true})
+}
+
+func (s *Server) loadUnknown(c *gin.Context) (store.UnknownKnowledgePoint, bool) {
+	id, err := strconv.ParseInt(c.Param("uid"), 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40026, "invalid knowledge point id")
+		return store.UnknownKnowledgePoint{}, false
+	}
+	u, err := s.Store.GetUnknownKnowledgePoint(c.Request.Context(), id)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40408, "knowledge point not found")
+			return store.UnknownKnowledgePoint{}, false
+		}
+		log.Printf("[ERROR] get unknown knowledge point: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50023, "query knowledge points failed")
+		return store.UnknownKnowledgePoint{}, false
+	}
+	return u, true
+}
+
+func (s *Server) reloadTaxonomy(c *gin.Context) {
+	if s.Taxonomy == nil {
+		return
+	}
+	if err := s.Taxonomy.Reload(c.Request.Context()); err != nil {
+		log.Printf("[WARN] reload taxonomy: %v", err)
+	}
+}
+
+func trimAll(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func (s *Server) handleAdminSafetyIncidents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, err := s.Store.ListSafetyIncidents(c.Request.Context(), limit)
+	if err != nil {
+		log.Printf("[ERROR] list safety incidents: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50026, "query safety incidents failed")
+		return
+	}
+	s.success(c, gin.H{"items": items})
+}
+
+func (s *Server) handleAdminQuestionCorrections(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, err := s.Store.ListQuestionCorrections(c.Request.Context(), limit)
+	if err != nil {
+		log.Printf("[ERROR] list question corrections: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50003, "query record failed")
+		return
+	}
+	s.success(c, gin.H{"items": items})
+}
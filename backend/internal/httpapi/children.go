@@ -0,0 +1,211 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+const maxChildNameRunes = 20
+
+type childReq struct {
+	Name       string `json:"name" form:"name"`
+	Grade      string `json:"grade" form:"grade"`
+	SchoolYear string `json:"schoolYear" form:"school_year"`
+	Edition    string `json:"edition" form:"edition"`
+	Region     string `json:"region" form:"region"`
+}
+
+func (s *Server) handleChildren(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	children, err := s.Store.ListChildren(c.Request.Context(), deviceID)
+	if err != nil {
+		log.Printf("[ERROR] list children: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50021, "query children failed")
+		return
+	}
+	s.success(c, gin.H{"items": children})
+}
+
+func (s *Server) handleChildCreate(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	req, ok := s.bindChild(c)
+	if !ok {
+		return
+	}
+	child, err := s.Store.CreateChild(c.Request.Context(), deviceID, req)
+	if err != nil {
+		log.Printf("[ERROR] create child: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50022, "save child failed")
+		return
+	}
+	s.success(c, gin.H{"child": child})
+}
+
+func (s *Server) handleChildUpdate(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("cid"), 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40023, "invalid child id")
+		return
+	}
+	req, ok := s.bindChild(c)
+	if !ok {
+		return
+	}
+	child, err := s.Store.UpdateChild(c.Request.Context(), id, deviceID, req)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40407, "child not found")
+			return
+		}
+		log.Printf("[ERROR] update child: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50022, "save child failed")
+		return
+	}
+	s.success(c, gin.H{"child": child})
+}
+
+func (s *Server) handleChildDelete(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("cid"), 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40023, "invalid child id")
+		return
+	}
+	if err := s.Store.DeleteChild(c.Request.Context(), id, deviceID); err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40407, "child not found")
+			return
+		}
+		log.Printf("[ERROR] delete child: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50022, "save child failed")
+		return
+	}
+	s.success(c, gin.H{"ok": true})
+}
+
+func (s *Server) bindChild(c *gin.Context) (store.ChildInput, bool) {
+	var req childReq
+	_ = c.ShouldBind(&req)
+	in := store.ChildInput{
+		Name:       strings.TrimSpace(req.Name),
+		Grade:      strings.TrimSpace(req.Grade),
+		SchoolYear: strings.TrimSpace(req.SchoolYear),
+		Edition:    s.edition(req.Edition),
+		Region:     strings.TrimSpace(req.Region),
+	}
+	if in.Name == "" || len([]rune(in.Name)) > maxChildNameRunes {
+		s.fail(c, http.StatusBadRequest, 40024, "child name required, at most 20 characters")
+		return store.ChildInput{}, false
+	}
+	return in, true
+}
+
+// edition canonicalizes a textbook edition name, keeping unknown editions as typed.
+func (s *Server) edition(name string) string {
+	name = strings.TrimSpace(name)
+	if e := s.Curriculum.Edition(name); e != "" {
+		return e
+	}
+	return name
+}
+
+// withLearner conditions an analysis on who it is for: the child's grade, edition
+// and region, each overridable by `grade`, `edition` and `region` on the request.
+// The edition's knowledge point names are added by analyze once the subject is known.
+func (s *Server) withLearner(c *gin.Context, child store.Child, in openai.AnalyzeInput) openai.AnalyzeInput {
+	in.Grade = firstNonEmpty(requestValue(c, "grade"), child.Grade)
+	in.Edition = firstNonEmpty(s.edition(requestValue(c, "edition")), child.Edition)
+	in.Region = firstNonEmpty(requestValue(c, "region"), child.Region)
+	return in
+}
+
+func requestValue(c *gin.Context, key string) string {
+	if v := strings.TrimSpace(c.PostForm(key)); v != "" {
+		return v
+	}
+	return strings.TrimSpace(c.Query(key))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// childFromRequest resolves the optional `child_id` form or query value to one of
+// the device's children. A zero Child means the request is not tied to a child.
+func (s *Server) childFromRequest(c *gin.Context, deviceID string) (store.Child, bool) {
+	raw := strings.TrimSpace(c.PostForm("child_id"))
+	if raw == "" {
+		raw = strings.TrimSpace(c.Query("child_id"))
+	}
+	if raw == "" {
+		return store.Child{}, true
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40023, "invalid child id")
+		return store.Child{}, false
+	}
+	child, err := s.Store.GetChild(c.Request.Context(), id, deviceID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40407, "child not found")
+			return store.Child{}, false
+		}
+		log.Printf("[ERROR] get child: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50021, "query children failed")
+		return store.Child{}, false
+	}
+	return child, true
+}
+
+// recordChild returns the child a stored record belongs to, or a zero Child when it
+// has none or the profile has since been removed.
+func (s *Server) recordChild(c *gin.Context, rec store.HomeworkRecord) store.Child {
+	if rec.ChildID == nil {
+		return store.Child{}
+	}
+	child, err := s.Store.GetChild(c.Request.Context(), *rec.ChildID, rec.DeviceID)
+	if err != nil {
+		if !store.IsNotFound(err) {
+			log.Printf("[WARN] get record child: %v", err)
+		}
+		return store.Child{}
+	}
+	return child
+}
+
+func derefID(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"log"
+	"net/http"
+	"strconv"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/gin-gonic/gin"
+	_ "golang.org/x/image/webp"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// minCropPixels is the smallest crop edge, in source pixels, worth sending to the model.
+const minCropPixels = 32
+
+var errCropTooSmall = errors.New("crop region too small")
+
+type cropReq struct {
+	X      float64 `json:"x" form:"x"`
+	Y      float64 `json:"y" form:"y"`
+	Width  float64 `json:"width" form:"width"`
+	Height float64 `json:"height" form:"height"`
+	Mode   string  `json:"mode" form:"mode"`
+}
+
+// handleCrop crops the stored source image of a record to a normalized rectangle and
+// analyzes just that region as a new record linked to the original.
+func (s *Server) handleCrop(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40004, "invalid id")
+		return
+	}
+	var req cropReq
+	if err := c.ShouldBind(&req); err != nil {
+		s.fail(c, http.StatusBadRequest, 40009, "invalid crop region")
+		return
+	}
+	box := openai.BoundingBox{X: req.X, Y: req.Y, Width: req.Width, Height: req.Height}
+	if !validRegion(box) {
+		s.fail(c, http.StatusBadRequest, 40009, "invalid crop region")
+		return
+	}
+	box = box.Clamp()
+	mode := normalizeMode(req.Mode)
+
+	rec, err := s.Store.GetHomeworkByIDAndDevice(c.Request.Context(), id, deviceID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40401, "record not found")
+			return
+		}
+		log.Printf("[ERROR] get homework for crop: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50003, "query record failed")
+		return
+	}
+	if rec.Kind == store.KindVoice || rec.Kind == store.KindText {
+		s.fail(c, http.StatusBadRequest, 40010, "source image cannot be cropped")
+		return
+	}
+
+	src, _, err := s.readStoredImage(rec.SourceImage)
+	if err != nil {
+		s.fail(c, http.StatusBadRequest, 40005, "image source missing")
+		return
+	}
+	cropped, err := cropImage(src, box)
+	if err != nil {
+		if errors.Is(err, errCropTooSmall) {
+			s.fail(c, http.StatusBadRequest, 40009, err.Error())
+			return
+		}
+		log.Printf("[ERROR] crop image: %v", err)
+		s.fail(c, http.StatusBadRequest, 40010, "source image cannot be cropped")
+		return
+	}
+	imageURL, err := s.saveUpload(cropped, ".jpg")
+	if err != nil {
+		log.Printf("[ERROR] save cropped image: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50002, "save record failed")
+		return
+	}
+
+	result, err := s.analyze(c, s.withLearner(c, s.recordChild(c, rec), openai.AnalyzeInput{Image: cropped, ContentType: "image/jpeg", Mode: mode}))
+	if err != nil {
+		s.failAnalyze(c, "analyze crop", err)
+		return
+	}
+
+	child, err := s.Store.CreateHomework(c.Request.Context(), store.NewHomework{
+		DeviceID:     deviceID,
+		Mode:         mode,
+		Kind:         store.KindCrop,
+		ParentID:     rec.ID,
+		ChildID:      derefID(rec.ChildID),
+		Subject:      result.Subject,
+		ImageURL:     imageURL,
+		QuestionText: result.QuestionText,
+		Grade:        result.SuggestedGrade,
+		Result:       result,
+		Region:       box,
+		Meta:         s.resultMeta(),
+	})
+	if err != nil {
+		log.Printf("[ERROR] create crop homework: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50002, "save record failed")
+		return
+	}
+
+	s.success(c, gin.H{"record": toHomeworkResp(child)})
+}
+
+// validRegion reports whether box is a non-empty rectangle inside the unit square,
+// allowing for float rounding at the right and bottom edges.
+func validRegion(box openai.BoundingBox) bool {
+	const eps = 1e-6
+	return box.X >= 0 && box.Y >= 0 && box.Width > 0 && box.Height > 0 &&
+		box.X+box.Width <= 1+eps && box.Y+box.Height <= 1+eps
+}
+
+// cropImage decodes src, cuts out the normalized box and re-encodes it as JPEG.
+func cropImage(src []byte, box openai.BoundingBox) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	rect := image.Rect(
+		b.Min.X+int(box.X*w),
+		b.Min.Y+int(box.Y*h),
+		b.Min.X+int((box.X+box.Width)*w+0.5),
+		b.Min.Y+int((box.Y+box.Height)*h+0.5),
+	).Intersect(b)
+	if rect.Dx() < minCropPixels || rect.Dy() < minCropPixels {
+		return nil, errCropTooSmall
+	}
+
+	sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, errors.New("image does not support cropping")
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, sub.SubImage(rect), &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
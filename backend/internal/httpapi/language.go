@@ -0,0 +1,335 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+)
+
+const languageKey = "language"
+
+type preferencesReq struct {
+	Language string `json:"language" form:"language"`
+}
+
+// requestLanguage resolves the output language: the `lang` parameter, then the
+// device's saved preference, then Accept-Language. ok is false when none of them
+// picked a supported language. The result is cached on the context.
+func (s *Server) requestLanguage(c *gin.Context) (lang string, ok bool) {
+	if v, exists := c.Get(languageKey); exists {
+		lang = v.(string)
+		return lang, lang != ""
+	}
+	lang = openai.NormalizeLanguage(requestValue(c, "lang"))
+	if lang == "" && s.Store != nil {
+		if deviceID := deviceIDFromRequest(c); deviceID != "" {
+			prefs, err := s.Store.GetDevicePreferences(c.Request.Context(), deviceID)
+			if err != nil {
+				log.Printf("[WARN] get device preferences: %v", err)
+			}
+			lang = prefs.Language
+		}
+	}
+	if lang == "" {
+		lang = acceptLanguage(c.GetHeader("Accept-Language"))
+	}
+	c.Set(languageKey, lang)
+	return lang, lang != ""
+}
+
+// language is the output language for generated content, DefaultLanguage unless
+// the request says otherwise.
+func (s *Server) language(c *gin.Context) string {
+	if lang, ok := s.requestLanguage(c); ok {
+		return lang
+	}
+	return openai.DefaultLanguage
+}
+
+// acceptLanguage picks the supported language with the highest q value.
+func acceptLanguage(header string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		lang := openai.NormalizeLanguage(tag)
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{lang, q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	if len(choices) == 0 {
+		return ""
+	}
+	return choices[0].lang
+}
+
+// messageLanguage is the language of API messages. It reuses the request
+// language when already resolved; otherwise the `lang` parameter and
+// Accept-Language come before the device preference, so most errors, 429
+// included, are answered without a database query.
+func (s *Server) messageLanguage(c *gin.Context) (string, bool) {
+	if v, exists := c.Get(languageKey); exists {
+		lang := v.(string)
+		return lang, lang != ""
+	}
+	if lang := openai.NormalizeLanguage(requestValue(c, "lang")); lang != "" {
+		return lang, true
+	}
+	if lang := acceptLanguage(c.GetHeader("Accept-Language")); lang != "" {
+		return lang, true
+	}
+	return s.requestLanguage(c)
+}
+
+// localize translates an API message into the request's language. Messages stay
+// in English when the request did not ask for a language, so existing clients
+// see no change.
+func (s *Server) localize(c *gin.Context, message string) string {
+	lang, ok := s.messageLanguage(c)
+	if !ok {
+		return message
+	}
+	if m, found := messageCatalog[lang][message]; found {
+		return m
+	}
+	return message
+}
+
+func (s *Server) handlePreferences(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	prefs, err := s.Store.GetDevicePreferences(c.Request.Context(), deviceID)
+	if err != nil {
+		log.Printf("[ERROR] get device preferences: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50028, "query preferences failed")
+		return
+	}
+	s.success(c, gin.H{"preferences": prefs})
+}
+
+func (s *Server) handlePreferencesUpdate(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	var req preferencesReq
+	_ = c.ShouldBind(&req)
+	lang := openai.NormalizeLanguage(req.Language)
+	if lang == "" && strings.TrimSpace(req.Language) != "" {
+		s.fail(c, http.StatusBadRequest, 40028, "unsupported language, expected zh-CN, zh-TW or en")
+		return
+	}
+	prefs, err := s.Store.SetDeviceLanguage(c.Request.Context(), deviceID, lang)
+	if err != nil {
+		log.Printf("[ERROR] save device preferences: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50029, "save preferences failed")
+		return
+	}
+	s.success(c, gin.H{"preferences": prefs})
+}
+
+// messageCatalog holds the Chinese API messages keyed by the English ones.
+// Messages missing here (e.g. wrapped validation errors) stay in English.
+var messageCatalog = map[string]map[string]string{
+	openai.LanguageZhCN: {
+		"admin token required": "需要管理员令牌",
+		"aliases required":     "请填写别名",
+		"analysis withheld by safety check, please try again": "分析结果未通过内容安全检查，请重试",
+		"analyze failed":                             "分析失败",
+		"analyze output invalid":                     "分析结果格式不正确，请重试",
+		"answer required":                            "请填写答案",
+		"audio file required":                        "请上传录音",
+		"child name required, at most 20 characters": "请填写孩子姓名，最多 20 个字",
+		"child not found":                            "孩子档案不存在",
+		"chunk exceeds declared size":                "分片超出声明的文件大小",
+		"chunk too large":                            "分片过大",
+		"complete upload failed":                     "完成上传失败",
+		"create upload failed":                       "创建上传失败",
+		"device_id required":                         "缺少设备 ID",
+		"empty chunk":                                "分片为空",
+		"generate report failed":                     "生成报告失败",
+		"image file required":                        "请上传图片",
+		"image source missing":                       "原图不存在",
+		"invalid child id":                           "孩子 ID 无效",
+		"invalid crop region":                        "裁剪区域无效",
+		"invalid id":                                 "ID 无效",
+		"invalid knowledge point id":                 "知识点 ID 无效",
+		"invalid mistake cause":                      "错因无效",
+		"invalid mistake id":                         "错题 ID 无效",
+		"invalid offset":                             "偏移量无效",
+		"invalid problem id":                         "练习题 ID 无效",
+		"invalid question index":                     "题目序号无效",
+		"invalid session id":                         "演练 ID 无效",
+		"invalid version id":                         "版本 ID 无效",
+		"invalid voice":                              "音色无效",
+		"invalid week, expected YYYY-MM-DD":          "日期无效，格式应为 YYYY-MM-DD",
+		"knowledge point not found":                  "知识点不存在",
+		"message content required":                   "请填写追问内容",
+		"message too long":                           "内容过长",
+		"mistake not found":                          "错题不存在",
+		"name required":                              "请填写名称",
+		"no question detected":                       "没有识别到题目",
+		"not found":                                  "不存在",
+		"offset mismatch":                            "偏移量不一致",
+		"page record cannot be regenerated, analyze a question instead": "整页记录不能重新生成，请选择一道题分析",
+		"page record has no analysis, analyze a question instead":       "整页记录没有分析结果，请选择一道题分析",
+		"problem not found":                                 "练习题不存在",
+		"query children failed":                             "查询孩子档案失败",
+		"query detail failed":                               "查询详情失败",
+		"query history failed":                              "查询历史记录失败",
+		"query knowledge points failed":                     "查询知识点失败",
+		"query messages failed":                             "查询对话失败",
+		"query mistakes failed":                             "查询错题失败",
+		"query practice failed":                             "查询练习题失败",
+		"query preferences failed":                          "查询设置失败",
+		"query record failed":                               "查询记录失败",
+		"query rehearsal failed":                            "查询演练失败",
+		"query safety incidents failed":                     "查询内容安全事件失败",
+		"query upload failed":                               "查询上传失败",
+		"query versions failed":                             "查询历史版本失败",
+		"question not found":                                "题目不存在",
+		"question text required, at most 1000 characters":   "请填写题目，最多 1000 字",
+		"question unreadable, please retake the photo":      "看不清题目，请重新拍照",
+		"rate limit exceeded":                               "请求过于频繁，请稍后再试",
+		"read chunk failed":                                 "读取分片失败",
+		"record is not a page":                              "该记录不是整页记录",
+		"recording inaudible, please record again":          "没有听清题目，请重新录音",
+		"record not found":                                  "记录不存在",
+		"rehearsal already finished":                        "演练已结束",
+		"rehearsal not found":                               "演练不存在",
+		"rehearsal turn limit reached, please finish":       "演练轮数已达上限，请结束演练",
+		"save child failed":                                 "保存孩子档案失败",
+		"save knowledge point failed":                       "保存知识点失败",
+		"save message failed":                               "保存对话失败",
+		"save mistake failed":                               "保存错题失败",
+		"save practice failed":                              "保存练习题失败",
+		"save preferences failed":                           "保存设置失败",
+		"save record failed":                                "保存记录失败",
+		"save rehearsal failed":                             "保存演练失败",
+		"say something to the child before finishing":       "结束前请先和孩子说几句",
+		"source image cannot be cropped":                    "原图无法裁剪",
+		"synthesize speech failed":                          "语音合成失败",
+		"transcribe audio failed":                           "语音识别失败",
+		"unsupported language, expected zh-CN, zh-TW or en": "不支持的语言，可选 zh-CN、zh-TW、en",
+		"update record failed":                              "更新记录失败",
+		"upload already completed":                          "上传已完成",
+		"upload data incomplete":                            "上传数据不完整",
+		"upload not completed":                              "上传尚未完成",
+		"upload not found or expired":                       "上传不存在或已过期",
+		"upload size required":                              "请填写文件大小",
+		"version not found":                                 "版本不存在",
+		"write chunk failed":                                "写入分片失败",
+	},
+	openai.LanguageZhTW: {
+		"admin token required": "需要管理員權杖",
+		"aliases required":     "請填寫別名",
+		"analysis withheld by safety check, please try again": "分析結果未通過內容安全檢查，請重試",
+		"analyze failed":                             "分析失敗",
+		"analyze output invalid":                     "分析結果格式不正確，請重試",
+		"answer required":                            "請填寫答案",
+		"audio file required":                        "請上傳錄音",
+		"child name required, at most 20 characters": "請填寫孩子姓名，最多 20 個字",
+		"child not found":                            "孩子檔案不存在",
+		"chunk exceeds declared size":                "分片超出宣告的檔案大小",
+		"chunk too large":                            "分片過大",
+		"complete upload failed":                     "完成上傳失敗",
+		"create upload failed":                       "建立上傳失敗",
+		"device_id required":                         "缺少裝置 ID",
+		"empty chunk":                                "分片為空",
+		"generate report failed":                     "產生報告失敗",
+		"image file required":                        "請上傳圖片",
+		"image source missing":                       "原圖不存在",
+		"invalid child id":                           "孩子 ID 無效",
+		"invalid crop region":                        "裁切區域無效",
+		"invalid id":                                 "ID 無效",
+		"invalid knowledge point id":                 "知識點 ID 無效",
+		"invalid mistake cause":                      "錯因無效",
+		"invalid mistake id":                         "錯題 ID 無效",
+		"invalid offset":                             "偏移量無效",
+		"invalid problem id":                         "練習題 ID 無效",
+		"invalid question index":                     "題目序號無效",
+		"invalid session id":                         "演練 ID 無效",
+		"invalid version id":                         "版本 ID 無效",
+		"invalid voice":                              "音色無效",
+		"invalid week, expected YYYY-MM-DD":          "日期無效，格式應為 YYYY-MM-DD",
+		"knowledge point not found":                  "知識點不存在",
+		"message content required":                   "請填寫追問內容",
+		"message too long":                           "內容過長",
+		"mistake not found":                          "錯題不存在",
+		"name required":                              "請填寫名稱",
+		"no question detected":                       "沒有辨識到題目",
+		"not found":                                  "不存在",
+		"offset mismatch":                            "偏移量不一致",
+		"page record cannot be regenerated, analyze a question instead": "整頁紀錄不能重新產生，請選擇一道題分析",
+		"page record has no analysis, analyze a question instead":       "整頁紀錄沒有分析結果，請選擇一道題分析",
+		"problem not found":                                 "練習題不存在",
+		"query children failed":                             "查詢孩子檔案失敗",
+		"query detail failed":                               "查詢詳情失敗",
+		"query history failed":                              "查詢歷史紀錄失敗",
+		"query knowledge points failed":                     "查詢知識點失敗",
+		"query messages failed":                             "查詢對話失敗",
+		"query mistakes failed":                             "查詢錯題失敗",
+		"query practice failed":                             "查詢練習題失敗",
+		"query preferences failed":                          "查詢設定失敗",
+		"query record failed":                               "查詢紀錄失敗",
+		"query rehearsal failed":                            "查詢演練失敗",
+		"query safety incidents failed":                     "查詢內容安全事件失敗",
+		"query upload failed":                               "查詢上傳失敗",
+		"query versions failed":                             "查詢歷史版本失敗",
+		"question not found":                                "題目不存在",
+		"question text required, at most 1000 characters":   "請填寫題目，最多 1000 字",
+		"question unreadable, please retake the photo":      "看不清題目，請重新拍照",
+		"rate limit exceeded":                               "請求過於頻繁，請稍後再試",
+		"read chunk failed":                                 "讀取分片失敗",
+		"record is not a page":                              "該紀錄不是整頁紀錄",
+		"recording inaudible, please record again":          "沒有聽清題目，請重新錄音",
+		"record not found":                                  "紀錄不存在",
+		"rehearsal already finished":                        "演練已結束",
+		"rehearsal not found":                               "演練不存在",
+		"rehearsal turn limit reached, please finish":       "演練輪數已達上限，請結束演練",
+		"save child failed":                                 "儲存孩子檔案失敗",
+		"save knowledge point failed":                       "儲存知識點失敗",
+		"save message failed":                               "儲存對話失敗",
+		"save mistake failed":                               "儲存錯題失敗",
+		"save practice failed":                              "儲存練習題失敗",
+		"save preferences failed":                           "儲存設定失敗",
+		"save record failed":                                "儲存紀錄失敗",
+		"save rehearsal failed":                             "儲存演練失敗",
+		"say something to the child before finishing":       "結束前請先和孩子說幾句",
+		"source image cannot be cropped":                    "原圖無法裁切",
+		"synthesize speech failed":                          "語音合成失敗",
+		"transcribe audio failed":                           "語音辨識失敗",
+		"unsupported language, expected zh-CN, zh-TW or en": "不支援的語言，可選 zh-CN、zh-TW、en",
+		"update record failed":                              "更新紀錄失敗",
+		"upload already completed":                          "上傳已完成",
+		"upload data incomplete":                            "上傳資料不完整",
+		"upload not completed":                              "上傳尚未完成",
+		"upload not found or expired":                       "上傳不存在或已過期",
+		"upload size required":                              "請填寫檔案大小",
+		"version not found":                                 "版本不存在",
+		"write chunk failed":                                "寫入分片失敗",
+	},
+}
@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+const maxMessageRunes = 500
+
+type messageReq struct {
+	Content string `json:"content" form:"content"`
+}
+
+// handleHomeworkMessage continues the conversation about a record with the parent's
+// follow-up question and returns the whole thread.
+func (s *Server) handleHomeworkMessage(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	var req messageReq
+	_ = c.ShouldBind(&req)
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		s.fail(c, http.StatusBadRequest, 40015, "message content required")
+		return
+	}
+	if len([]rune(content)) > maxMessageRunes {
+		s.fail(c, http.StatusBadRequest, 40016, "message too long")
+		return
+	}
+
+	if rec.Kind == store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40008, "page record has no analysis, analyze a question instead")
+		return
+	}
+	thread, err := s.Store.ListHomeworkMessages(c.Request.Context(), rec.ID)
+	if err != nil {
+		log.Printf("[ERROR] list homework messages: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50013, "query messages failed")
+		return
+	}
+
+	var result openai.AnalyzeResult
+	_ = json.Unmarshal(rec.ResultJSONRaw, &result)
+	in := openai.FollowUpInput{
+		Mode:         rec.Mode,
+		QuestionText: rec.QuestionText,
+		Result:       result,
+		Message:      content,
+	}
+	// The photo may have been removed by retention; the text context still works.
+	// A voice record's source is the recording, whose transcript is QuestionText.
+	if rec.SourceImage != "" && rec.Kind != store.KindVoice {
+		if b, contentType, err := s.readStoredImage(rec.SourceImage); err == nil {
+			in.Image, in.ContentType = b, contentType
+		}
+	}
+	for _, m := range thread {
+		in.History = append(in.History, openai.ChatTurn{Role: m.Role, Content: m.Content})
+	}
+
+	reply, err := s.followUp(c, in)
+	if err != nil {
+		s.failAnalyze(c, "follow up", err)
+		return
+	}
+	if err := s.Store.AppendHomeworkExchange(c.Request.Context(), rec.ID, content, reply); err != nil {
+		log.Printf("[ERROR] append homework messages: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50014, "save message failed")
+		return
+	}
+	thread, err = s.Store.ListHomeworkMessages(c.Request.Context(), rec.ID)
+	if err != nil {
+		log.Printf("[ERROR] list homework messages: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50013, "query messages failed")
+		return
+	}
+
+	s.success(c, gin.H{"messages": thread})
+}
+
+func (s *Server) followUp(c *gin.Context, in openai.FollowUpInput) (string, error) {
+	in.Language = s.language(c)
+	if s.AnalyzeMock {
+		return mockFollowUp(in.Mode), nil
+	}
+	if s.OpenAI == nil || strings.TrimSpace(s.OpenAI.APIKey) == "" {
+		return "", errOpenAIConfigMissing
+	}
+	return s.OpenAI.FollowUp(c.Request.Context(), in)
+}
+
+func mockFollowUp(mode string) string {
+	if mode == "noanswer" {
+		return "先别急着告诉孩子对不对，可以请孩子把算式拆开再说一遍：24×10 是多少？24×5 又是多少？让孩子自己比较两部分加起来的结果。"
+	}
+	return "可以先肯定孩子愿意尝试，再请孩子说说是怎么算出来的。如果孩子只算了 24×10，就提醒一下：15 里除了 10 还有几？那部分也要乘上 24。"
+}
@@ -0,0 +1,170 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+var mistakeCauses = map[string]bool{
+	"concept":     true, // 概念不清
+	"calculation": true, // 计算错误
+	"careless":    true, // 粗心
+	"reading":     true, // 审题不清
+	"method":      true, // 方法不会
+	"other":       true,
+}
+
+type mistakeReq struct {
+	WrongAnswer string `json:"wrongAnswer" form:"wrong_answer"`
+	Cause       string `json:"cause" form:"cause"`
+	Note        string `json:"note" form:"note"`
+	Subject     string `json:"subject" form:"subject"`
+}
+
+type reviewReq struct {
+	Remembered bool `json:"remembered" form:"remembered"`
+}
+
+// handleMarkMistake puts a record into the notebook, or updates it if already there.
+func (s *Server) handleMarkMistake(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	if rec.Kind == store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40008, "page record has no analysis, analyze a question instead")
+		return
+	}
+	var req mistakeReq
+	_ = c.ShouldBind(&req)
+	cause := strings.TrimSpace(strings.ToLower(req.Cause))
+	if cause == "" {
+		cause = "other"
+	}
+	if !mistakeCauses[cause] {
+		s.fail(c, http.StatusBadRequest, 40021, "invalid mistake cause")
+		return
+	}
+
+	var result openai.AnalyzeResult
+	_ = json.Unmarshal(rec.ResultJSONRaw, &result)
+	subject := strings.TrimSpace(strings.ToLower(req.Subject))
+	if subject == "" {
+		subject = rec.Subject
+	}
+	m, err := s.Store.UpsertMistake(c.Request.Context(), store.UpsertMistake{
+		RecordID:        rec.ID,
+		DeviceID:        rec.DeviceID,
+		WrongAnswer:     strings.TrimSpace(req.WrongAnswer),
+		Cause:           cause,
+		Note:            strings.TrimSpace(req.Note),
+		Subject:         subject,
+		KnowledgePoints: result.KnowledgePoints,
+		NextReviewOn:    today().AddDate(0, 0, store.ReviewIntervals[0]),
+	})
+	if err != nil {
+		log.Printf("[ERROR] upsert mistake: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50019, "save mistake failed")
+		return
+	}
+	s.success(c, gin.H{"mistake": m})
+}
+
+func (s *Server) handleUnmarkMistake(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	if err := s.Store.DeleteMistakeByRecord(c.Request.Context(), rec.ID, rec.DeviceID); err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40406, "mistake not found")
+			return
+		}
+		log.Printf("[ERROR] delete mistake: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50019, "save mistake failed")
+		return
+	}
+	s.success(c, gin.H{"ok": true})
+}
+
+// handleMistakes lists the notebook, filtered by subject, knowledge point and cause.
+func (s *Server) handleMistakes(c *gin.Context) {
+	s.listMistakes(c, nil)
+}
+
+// handleMistakesDue lists the mistakes scheduled for review today or earlier.
+func (s *Server) handleMistakesDue(c *gin.Context) {
+	d := today()
+	s.listMistakes(c, &d)
+}
+
+func (s *Server) listMistakes(c *gin.Context, dueOn *time.Time) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	items, err := s.Store.ListMistakes(c.Request.Context(), deviceID, store.MistakeFilter{
+		Subject:        strings.TrimSpace(strings.ToLower(c.Query("subject"))),
+		KnowledgePoint: strings.TrimSpace(c.Query("knowledge_point")),
+		Cause:          strings.TrimSpace(strings.ToLower(c.Query("cause"))),
+		DueOn:          dueOn,
+	}, 200)
+	if err != nil {
+		log.Printf("[ERROR] list mistakes: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50020, "query mistakes failed")
+		return
+	}
+	s.success(c, gin.H{"items": items})
+}
+
+// handleMistakeReview records a review: remembering advances to the next interval,
+// forgetting starts the schedule over.
+func (s *Server) handleMistakeReview(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("mid"), 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40022, "invalid mistake id")
+		return
+	}
+	var req reviewReq
+	_ = c.ShouldBind(&req)
+
+	m, err := s.Store.GetMistake(c.Request.Context(), id, deviceID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40406, "mistake not found")
+			return
+		}
+		log.Printf("[ERROR] get mistake: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50020, "query mistakes failed")
+		return
+	}
+
+	stage, next, mastered := store.NextReview(m.ReviewStage, req.Remembered, today())
+	updated, err := s.Store.UpdateMistakeReview(c.Request.Context(), m.ID, deviceID, stage, next, mastered)
+	if err != nil {
+		log.Printf("[ERROR] update mistake review: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50019, "save mistake failed")
+		return
+	}
+	s.success(c, gin.H{"mistake": updated})
+}
+
+func today() time.Time {
+	y, m, d := time.Now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,176 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// handleAnalyzePage detects every question on a worksheet photo and stores them on a
+// page record so the parent can pick which one to analyze.
+func (s *Server) handleAnalyzePage(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+
+	child, ok := s.childFromRequest(c, deviceID)
+	if !ok {
+		return
+	}
+	bytes, contentType, imageURL, ok := s.imageFromRequest(c, deviceID)
+	if !ok {
+		return
+	}
+
+	questions, err := s.detectQuestions(c, bytes, contentType)
+	if err != nil {
+		s.failAnalyze(c, "detect questions", err)
+		return
+	}
+	if len(questions) == 0 {
+		s.fail(c, http.StatusUnprocessableEntity, 42201, "no question detected")
+		return
+	}
+
+	texts := make([]string, 0, len(questions))
+	for _, q := range questions {
+		texts = append(texts, q.QuestionText)
+	}
+	rec, err := s.Store.CreateHomework(c.Request.Context(), store.NewHomework{
+		DeviceID:      deviceID,
+		Mode:          normalizeMode(c.PostForm("mode")),
+		Kind:          store.KindPage,
+		ChildID:       child.ID,
+		ImageURL:      imageURL,
+		QuestionText:  strings.Join(texts, "\n"),
+		Result:        openai.AnalyzeResult{},
+		PageQuestions: questions,
+	})
+	if err != nil {
+		log.Printf("[ERROR] create page homework: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50002, "save record failed")
+		return
+	}
+
+	s.success(c, gin.H{"record": toHomeworkResp(rec)})
+}
+
+// handleAnalyzePageQuestion runs a focused analysis of one detected question and
+// stores it as a child of the page record.
+func (s *Server) handleAnalyzePageQuestion(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40004, "invalid id")
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index <= 0 {
+		s.fail(c, http.StatusBadRequest, 40006, "invalid question index")
+		return
+	}
+	mode := normalizeMode(c.PostForm("mode"))
+	if mode == "guided" {
+		if qmode := normalizeMode(c.Query("mode")); qmode != "guided" {
+			mode = qmode
+		}
+	}
+
+	page, err := s.Store.GetHomeworkByIDAndDevice(c.Request.Context(), id, deviceID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40401, "record not found")
+			return
+		}
+		log.Printf("[ERROR] get page homework: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50003, "query record failed")
+		return
+	}
+	if page.Kind != store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40007, "record is not a page")
+		return
+	}
+
+	question, ok := findQuestion(toHomeworkResp(page).Questions, index)
+	if !ok {
+		s.fail(c, http.StatusNotFound, 40402, "question not found")
+		return
+	}
+
+	b, contentType, err := s.readStoredImage(page.SourceImage)
+	if err != nil {
+		s.fail(c, http.StatusBadRequest, 40005, "image source missing")
+		return
+	}
+
+	result, err := s.analyze(c, s.withLearner(c, s.recordChild(c, page), openai.AnalyzeInput{Image: b, ContentType: contentType, Mode: mode, Focus: question.QuestionText}))
+	if err != nil {
+		s.failAnalyze(c, "analyze page question", err)
+		return
+	}
+	questionText := result.QuestionText
+	if questionText == "" {
+		questionText = question.QuestionText
+	}
+
+	rec, err := s.Store.CreateHomework(c.Request.Context(), store.NewHomework{
+		DeviceID:     deviceID,
+		Mode:         mode,
+		Kind:         store.KindQuestion,
+		ParentID:     page.ID,
+		ChildID:      derefID(page.ChildID),
+		Subject:      result.Subject,
+		ImageURL:     page.SourceImage,
+		QuestionText: questionText,
+		Grade:        result.SuggestedGrade,
+		Result:       result,
+		Region:       question.BBox,
+		Meta:         s.resultMeta(),
+	})
+	if err != nil {
+		log.Printf("[ERROR] create page question homework: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50002, "save record failed")
+		return
+	}
+
+	s.success(c, gin.H{"record": toHomeworkResp(rec)})
+}
+
+func (s *Server) detectQuestions(c *gin.Context, imageBytes []byte, contentType string) ([]openai.DetectedQuestion, error) {
+	if s.AnalyzeMock {
+		return mockPageQuestions(), nil
+	}
+	if s.OpenAI == nil || strings.TrimSpace(s.OpenAI.APIKey) == "" {
+		return nil, errOpenAIConfigMissing
+	}
+	return s.OpenAI.DetectQuestions(c.Request.Context(), imageBytes, contentType)
+}
+
+func findQuestion(questions []openai.DetectedQuestion, index int) (openai.DetectedQuestion, bool) {
+	for _, q := range questions {
+		if q.Index == index {
+			return q, true
+		}
+	}
+	return openai.DetectedQuestion{}, false
+}
+
+func mockPageQuestions() []openai.DetectedQuestion {
+	return []openai.DetectedQuestion{
+		{Index: 1, QuestionText: "24 × 15 = ?", BBox: openai.BoundingBox{X: 0.05, Y: 0.05, Width: 0.9, Height: 0.12}},
+		{Index: 2, QuestionText: "36 × 25 = ?", BBox: openai.BoundingBox{X: 0.05, Y: 0.2, Width: 0.9, Height: 0.12}},
+		{Index: 3, QuestionText: "小明有 48 颗糖，平均分给 6 个同学，每人分到几颗？", BBox: openai.BoundingBox{X: 0.05, Y: 0.35, Width: 0.9, Height: 0.2}},
+	}
+}
@@ -0,0 +1,167 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/answer"
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// practiceRevealAfter is how many wrong attempts unlock the answer of a problem.
+const practiceRevealAfter = 2
+
+type practiceReq struct {
+	Count int `json:"count" form:"count"`
+}
+
+type practiceAnswerReq struct {
+	Answer string `json:"answer" form:"answer"`
+}
+
+// practiceView is a problem as shown to the family: the hint appears after a wrong
+// attempt and the answer once solved or after practiceRevealAfter misses.
+type practiceView struct {
+	store.PracticeProblem
+	Hint   string `json:"hint,omitempty"`
+	Answer string `json:"answer,omitempty"`
+}
+
+func toPracticeView(p store.PracticeProblem) practiceView {
+	v := practiceView{PracticeProblem: p}
+	if p.Attempts > 0 {
+		v.Hint = p.Hint
+	}
+	if (p.IsCorrect != nil && *p.IsCorrect) || p.Attempts >= practiceRevealAfter {
+		v.Answer = p.Answer
+	}
+	return v
+}
+
+func toPracticeViews(items []store.PracticeProblem) []practiceView {
+	out := make([]practiceView, 0, len(items))
+	for _, p := range items {
+		out = append(out, toPracticeView(p))
+	}
+	return out
+}
+
+// handlePracticeGenerate creates 3-5 variant problems for an analyzed record.
+func (s *Server) handlePracticeGenerate(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	if rec.Kind == store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40008, "page record has no analysis, analyze a question instead")
+		return
+	}
+	var req practiceReq
+	_ = c.ShouldBind(&req)
+
+	var result openai.AnalyzeResult
+	_ = json.Unmarshal(rec.ResultJSONRaw, &result)
+	problems, err := s.generatePractice(c, openai.PracticeInput{
+		QuestionText: rec.QuestionText,
+		Result:       result,
+		Count:        req.Count,
+	})
+	if err != nil {
+		s.failAnalyze(c, "generate practice", err)
+		return
+	}
+
+	batch := make([]store.NewPracticeProblem, 0, len(problems))
+	for _, p := range problems {
+		batch = append(batch, store.NewPracticeProblem{Question: p.Question, Answer: p.Answer, Hint: p.Hint})
+	}
+	created, err := s.Store.CreatePracticeProblems(c.Request.Context(), rec.ID, batch)
+	if err != nil {
+		log.Printf("[ERROR] create practice problems: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50017, "save practice failed")
+		return
+	}
+	s.success(c, gin.H{"problems": toPracticeViews(created)})
+}
+
+func (s *Server) handlePracticeList(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	items, err := s.Store.ListPracticeProblems(c.Request.Context(), rec.ID)
+	if err != nil {
+		log.Printf("[ERROR] list practice problems: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50018, "query practice failed")
+		return
+	}
+	s.success(c, gin.H{"problems": toPracticeViews(items)})
+}
+
+// handlePracticeAnswer checks the child's answer to one problem.
+func (s *Server) handlePracticeAnswer(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	pid, err := strconv.ParseInt(c.Param("pid"), 10, 64)
+	if err != nil || pid <= 0 {
+		s.fail(c, http.StatusBadRequest, 40019, "invalid problem id")
+		return
+	}
+	var req practiceAnswerReq
+	_ = c.ShouldBind(&req)
+	given := strings.TrimSpace(req.Answer)
+	if given == "" {
+		s.fail(c, http.StatusBadRequest, 40020, "answer required")
+		return
+	}
+
+	p, err := s.Store.GetPracticeProblem(c.Request.Context(), pid, rec.ID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40405, "problem not found")
+			return
+		}
+		log.Printf("[ERROR] get practice problem: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50018, "query practice failed")
+		return
+	}
+
+	correct := answer.Equal(p.Answer, given)
+	updated, err := s.Store.RecordPracticeAnswer(c.Request.Context(), p.ID, given, correct)
+	if err != nil {
+		log.Printf("[ERROR] record practice answer: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50017, "save practice failed")
+		return
+	}
+	s.success(c, gin.H{"correct": correct, "problem": toPracticeView(updated)})
+}
+
+func (s *Server) generatePractice(c *gin.Context, in openai.PracticeInput) ([]openai.PracticeProblem, error) {
+	in.Language = s.language(c)
+	if s.AnalyzeMock {
+		return mockPractice(openai.ClampPracticeCount(in.Count)), nil
+	}
+	if s.OpenAI == nil || strings.TrimSpace(s.OpenAI.APIKey) == "" {
+		return nil, errOpenAIConfigMissing
+	}
+	return s.OpenAI.GeneratePractice(c.Request.Context(), in)
+}
+
+func mockPractice(count int) []openai.PracticeProblem {
+	all := []openai.PracticeProblem{
+		{Question: "23 × 15 = ?", Answer: "345", Hint: "把 15 拆成 10 和 5 试试。"},
+		{Question: "32 × 15 = ?", Answer: "480", Hint: "先算 32×10，再算 32×5。"},
+		{Question: "24 × 25 = ?", Answer: "600", Hint: "25 可以拆成 20 和 5。"},
+		{Question: "18 × 15 = ?", Answer: "270", Hint: "18×10 和 18×5 各是多少？"},
+		{Question: "26 × 15 = ?", Answer: "390", Hint: "别忘了把两部分加起来。"},
+	}
+	return all[:count]
+}
@@ -64,3 +64,13 @@ func (d *DeviceLimiter) Allow(deviceID string) bool {
 	b.Tokens--
 	return true
 }
+
+// Refund gives back the token of a request that should not count against the
+// device, e.g. an analysis that could not read the photo.
+func (d *DeviceLimiter) Refund(deviceID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if b, ok := d.buckets[deviceID]; ok && b.Tokens+1 <= b.Capacity {
+		b.Tokens++
+	}
+}
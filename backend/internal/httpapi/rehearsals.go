@@ -0,0 +1,210 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// maxRehearsalTurns caps the parent lines in one practice session.
+const maxRehearsalTurns = 20
+
+// handleRehearsalStart opens a practice dialogue where the server plays the child.
+func (s *Server) handleRehearsalStart(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	if rec.Kind == store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40008, "page record has no analysis, analyze a question instead")
+		return
+	}
+
+	opening, err := s.childReply(c, rehearsalInput(rec, nil, ""))
+	if err != nil {
+		s.failAnalyze(c, "rehearsal opening", err)
+		return
+	}
+	sess, err := s.Store.CreateRehearsal(c.Request.Context(), rec.ID, rec.DeviceID, opening)
+	if err != nil {
+		log.Printf("[ERROR] create rehearsal: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50015, "save rehearsal failed")
+		return
+	}
+	s.success(c, gin.H{"session": sess})
+}
+
+func (s *Server) handleRehearsalDetail(c *gin.Context) {
+	_, sess, ok := s.loadRehearsal(c)
+	if !ok {
+		return
+	}
+	s.success(c, gin.H{"session": sess})
+}
+
+// handleRehearsalTurn takes the parent's line and answers as the child.
+func (s *Server) handleRehearsalTurn(c *gin.Context) {
+	rec, sess, ok := s.loadRehearsal(c)
+	if !ok {
+		return
+	}
+	if sess.Status != store.RehearsalActive {
+		s.fail(c, http.StatusConflict, 40905, "rehearsal already finished")
+		return
+	}
+	var req messageReq
+	_ = c.ShouldBind(&req)
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		s.fail(c, http.StatusBadRequest, 40015, "message content required")
+		return
+	}
+	if len([]rune(content)) > maxMessageRunes {
+		s.fail(c, http.StatusBadRequest, 40016, "message too long")
+		return
+	}
+	if parentTurns(sess) >= maxRehearsalTurns {
+		s.fail(c, http.StatusConflict, 40906, "rehearsal turn limit reached, please finish")
+		return
+	}
+
+	reply, err := s.childReply(c, rehearsalInput(rec, sess.Turns, content))
+	if err != nil {
+		s.failAnalyze(c, "rehearsal reply", err)
+		return
+	}
+	if err := s.Store.AppendRehearsalTurns(c.Request.Context(), sess.ID, content, reply); err != nil {
+		log.Printf("[ERROR] append rehearsal turns: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50015, "save rehearsal failed")
+		return
+	}
+	s.respondRehearsal(c, rec, sess.ID)
+}
+
+// handleRehearsalFinish scores the dialogue and closes the session.
+func (s *Server) handleRehearsalFinish(c *gin.Context) {
+	rec, sess, ok := s.loadRehearsal(c)
+	if !ok {
+		return
+	}
+	if sess.Status != store.RehearsalActive {
+		s.success(c, gin.H{"session": sess})
+		return
+	}
+	if parentTurns(sess) == 0 {
+		s.fail(c, http.StatusBadRequest, 40017, "say something to the child before finishing")
+		return
+	}
+
+	score, err := s.scoreRehearsal(c, rehearsalInput(rec, sess.Turns, ""))
+	if err != nil {
+		s.failAnalyze(c, "rehearsal score", err)
+		return
+	}
+	if err := s.Store.FinishRehearsal(c.Request.Context(), sess.ID, rec.DeviceID, score); err != nil && !store.IsNotFound(err) {
+		log.Printf("[ERROR] finish rehearsal: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50015, "save rehearsal failed")
+		return
+	}
+	s.respondRehearsal(c, rec, sess.ID)
+}
+
+func (s *Server) loadRehearsal(c *gin.Context) (store.HomeworkRecord, store.RehearsalSession, bool) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return store.HomeworkRecord{}, store.RehearsalSession{}, false
+	}
+	sid, err := strconv.ParseInt(c.Param("sid"), 10, 64)
+	if err != nil || sid <= 0 {
+		s.fail(c, http.StatusBadRequest, 40018, "invalid session id")
+		return store.HomeworkRecord{}, store.RehearsalSession{}, false
+	}
+	sess, err := s.Store.GetRehearsal(c.Request.Context(), sid, rec.ID, rec.DeviceID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40404, "rehearsal not found")
+			return store.HomeworkRecord{}, store.RehearsalSession{}, false
+		}
+		log.Printf("[ERROR] get rehearsal: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50016, "query rehearsal failed")
+		return store.HomeworkRecord{}, store.RehearsalSession{}, false
+	}
+	return rec, sess, true
+}
+
+func (s *Server) respondRehearsal(c *gin.Context, rec store.HomeworkRecord, id int64) {
+	sess, err := s.Store.GetRehearsal(c.Request.Context(), id, rec.ID, rec.DeviceID)
+	if err != nil {
+		log.Printf("[ERROR] get rehearsal: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50016, "query rehearsal failed")
+		return
+	}
+	s.success(c, gin.H{"session": sess})
+}
+
+func rehearsalInput(rec store.HomeworkRecord, turns []store.RehearsalTurn, message string) openai.RehearsalInput {
+	var result openai.AnalyzeResult
+	_ = json.Unmarshal(rec.ResultJSONRaw, &result)
+	in := openai.RehearsalInput{QuestionText: rec.QuestionText, Result: result, Message: message}
+	for _, t := range turns {
+		in.Turns = append(in.Turns, openai.ChatTurn{Role: t.Role, Content: t.Content})
+	}
+	return in
+}
+
+func parentTurns(sess store.RehearsalSession) int {
+	n := 0
+	for _, t := range sess.Turns {
+		if t.Role == store.RoleParent {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Server) childReply(c *gin.Context, in openai.RehearsalInput) (string, error) {
+	in.Language = s.language(c)
+	if s.AnalyzeMock {
+		return mockChildReply(in), nil
+	}
+	if s.OpenAI == nil || strings.TrimSpace(s.OpenAI.APIKey) == "" {
+		return "", errOpenAIConfigMissing
+	}
+	return s.OpenAI.ChildReply(c.Request.Context(), in)
+}
+
+func (s *Server) scoreRehearsal(c *gin.Context, in openai.RehearsalInput) (openai.RehearsalScore, error) {
+	in.Language = s.language(c)
+	if s.AnalyzeMock {
+		return mockRehearsalScore(), nil
+	}
+	if s.OpenAI == nil || strings.TrimSpace(s.OpenAI.APIKey) == "" {
+		return openai.RehearsalScore{}, errOpenAIConfigMissing
+	}
+	return s.OpenAI.ScoreRehearsal(c.Request.Context(), in)
+}
+
+func mockChildReply(in openai.RehearsalInput) string {
+	stuck := in.Result.ChildStuckPoints
+	if len(stuck) == 0 {
+		return "嗯……我不太明白，这题要先算哪一步呀？"
+	}
+	return "我有点糊涂：" + stuck[len(in.Turns)/2%len(stuck)]
+}
+
+func mockRehearsalScore() openai.RehearsalScore {
+	return openai.RehearsalScore{
+		GaveAwayAnswer: false,
+		Score:          82,
+		Summary:        "整体以提问引导为主，孩子有机会自己想。",
+		Strengths:      []string{"先让孩子说出自己的想法", "没有直接给出答案"},
+		Suggestions:    []string{"孩子卡住时可以先让他估一估结果的范围，再追问拆分的理由。"},
+	}
+}
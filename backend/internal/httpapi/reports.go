@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/report"
+)
+
+// handleWeeklyReport returns the weekly report of the device or one of its
+// children for the week containing `week` (YYYY-MM-DD, default this week),
+// generating or refreshing it when the week's records changed.
+func (s *Server) handleWeeklyReport(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	child, ok := s.childFromRequest(c, deviceID)
+	if !ok {
+		return
+	}
+	week := time.Now()
+	if raw := strings.TrimSpace(c.Query("week")); raw != "" {
+		d, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			s.fail(c, http.StatusBadRequest, 40027, "invalid week, expected YYYY-MM-DD")
+			return
+		}
+		week = d
+	}
+
+	gen := &report.Generator{Store: s.Store, Narrate: s.weeklyNarrator()}
+	rep, err := gen.Generate(c.Request.Context(), report.Target{
+		DeviceID:  deviceID,
+		ChildID:   child.ID,
+		ChildName: child.Name,
+		Grade:     child.Grade,
+		Language:  s.language(c),
+	}, week)
+	if err != nil {
+		log.Printf("[ERROR] weekly report: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50025, "generate report failed")
+		return
+	}
+	s.success(c, gin.H{"report": rep})
+}
+
+// weeklyNarrator picks how the report narrative is written: a template in mock
+// mode, the model when configured, none when disabled.
+func (s *Server) weeklyNarrator() func(context.Context, report.Target, report.Stats) (string, error) {
+	if !s.ReportNarrative {
+		return nil
+	}
+	if s.AnalyzeMock {
+		return func(_ context.Context, t report.Target, st report.Stats) (string, error) {
+			return mockWeeklyNarrative(t, st), nil
+		}
+	}
+	if s.OpenAI == nil || strings.TrimSpace(s.OpenAI.APIKey) == "" {
+		return nil
+	}
+	return func(ctx context.Context, t report.Target, st report.Stats) (string, error) {
+		b, err := json.Marshal(st)
+		if err != nil {
+			return "", err
+		}
+		return s.OpenAI.WeeklyNarrative(ctx, t.ChildName, t.Grade, string(b), t.Language)
+	}
+}
+
+func mockWeeklyNarrative(t report.Target, st report.Stats) string {
+	who := t.ChildName
+	if who == "" {
+		who = "孩子"
+	}
+	text := fmt.Sprintf("%s本周一共做了 %d 道题，有 %d 天在坚持练习。", who, st.Total, st.ActiveDays)
+	if len(st.Recurring) > 0 {
+		text += "「" + st.Recurring[0] + "」出现了好几次，下周可以挑两道同类题再练一练。"
+	}
+	return text
+}
@@ -3,9 +3,10 @@ package httpapi
 import (
 	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
-	"io"
 	"log"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -16,16 +17,35 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"whatsdot-aibuddy/backend/internal/curriculum"
+	"whatsdot-aibuddy/backend/internal/media"
 	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/safety"
 	"whatsdot-aibuddy/backend/internal/store"
+	"whatsdot-aibuddy/backend/internal/taxonomy"
 )
 
 type Server struct {
-	Store       *store.Store
-	OpenAI      *openai.Client
-	UploadDir   string
-	AnalyzeMock bool
-	Limiter     *DeviceLimiter
+	Store  *store.Store
+	OpenAI *openai.Client
+	// Speaker reads child-facing text aloud; nil disables the speech endpoint.
+	Speaker *openai.Speaker
+	// Transcriber turns spoken questions into text for voice analysis.
+	Transcriber   *openai.Transcriber
+	AudioMaxBytes int64
+	UploadDir     string
+	AnalyzeMock   bool
+	Limiter       *DeviceLimiter
+	Curriculum    *curriculum.Catalog
+	Taxonomy      *taxonomy.Taxonomy
+	AdminToken    string
+	// ReportNarrative adds a model-written narrative to weekly reports.
+	ReportNarrative bool
+
+	UploadLimits media.Limits
+
+	// uploadLocks keeps concurrent chunk writes of one upload session apart.
+	uploadLocks keyedMutex
 }
 
 type apiResp struct {
@@ -35,17 +55,42 @@ type apiResp struct {
 }
 
 type homeworkResp struct {
-	ID             int64                `json:"id"`
-	Mode           string               `json:"mode"`
-	SourceImage    string               `json:"sourceImageUrl"`
-	QuestionText   string               `json:"questionText"`
-	SuggestedGrade string               `json:"suggestedGrade"`
-	Result         openai.AnalyzeResult `json:"result"`
-	SolvedAt       time.Time            `json:"solvedAt"`
+	ID             int64                     `json:"id"`
+	Kind           string                    `json:"kind"`
+	ParentID       *int64                    `json:"parentId,omitempty"`
+	ChildID        *int64                    `json:"childId,omitempty"`
+	Mode           string                    `json:"mode"`
+	SourceImage    string                    `json:"sourceImageUrl"`
+	QuestionText   string                    `json:"questionText"`
+	SuggestedGrade string                    `json:"suggestedGrade"`
+	Result         openai.AnalyzeResult      `json:"result"`
+	Questions      []openai.DetectedQuestion `json:"questions,omitempty"`
+	Region         *openai.BoundingBox       `json:"region,omitempty"`
+	Children       []store.HistoryItem       `json:"children,omitempty"`
+	Messages       []store.HomeworkMessage   `json:"messages,omitempty"`
+	Mistake        *store.Mistake            `json:"mistake,omitempty"`
+	Audio          *recordAudio              `json:"audio,omitempty"`
+	// RecognizedQuestionText is set once the parent corrected QuestionText.
+	RecognizedQuestionText *string `json:"recognizedQuestionText,omitempty"`
+	// CurrentVersionID is the selected result version; see GET /homework/:id/versions.
+	CurrentVersionID *int64    `json:"currentVersionId,omitempty"`
+	SolvedAt         time.Time `json:"solvedAt"`
 }
 
 var errOpenAIConfigMissing = errors.New("openai not configured: set OPENAI_API_KEY or enable ANALYZE_MOCK=true")
 
+// unreadableError is returned by analyze when the model could not read the photo;
+// the result only carries the legibility assessment and retake tips. message
+// overrides the photo retake message.
+type unreadableError struct {
+	result  openai.AnalyzeResult
+	message string
+}
+
+func (e *unreadableError) Error() string {
+	return fmt.Sprintf("question unreadable (legibility=%s confidence=%.2f)", e.result.Legibility, e.result.Confidence)
+}
+
 func (s *Server) Engine() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
@@ -60,11 +105,65 @@ func (s *Server) Engine() *gin.Engine {
 		s.success(c, gin.H{"ok": true, "time": time.Now().Format(time.RFC3339)})
 	})
 
+	// Chunk PUTs and status checks are many small requests, so only opening and
+	// completing an upload count against the device rate limit.
+	uploads := r.Group("/api/v1/uploads")
+	{
+		uploads.POST("", s.withRateLimit(), s.handleUploadInit)
+		uploads.GET("/:uploadId", s.handleUploadStatus)
+		uploads.PUT("/:uploadId", s.handleUploadChunk)
+		uploads.POST("/:uploadId/complete", s.withRateLimit(), s.handleUploadComplete)
+	}
+
+	admin := r.Group("/api/v1/admin")
+	admin.Use(s.adminOnly())
+	{
+		admin.GET("/metrics", gin.WrapH(expvar.Handler()))
+		admin.GET("/knowledge-points", s.handleAdminKnowledgePoints)
+		admin.POST("/knowledge-points", s.handleAdminKnowledgePointCreate)
+		admin.POST("/knowledge-points/:pid/aliases", s.handleAdminKnowledgeAliases)
+		admin.GET("/knowledge-points/unknown", s.handleAdminUnknownKnowledge)
+		admin.POST("/knowledge-points/unknown/:uid/resolve", s.handleAdminResolveUnknown)
+		admin.POST("/knowledge-points/unknown/:uid/reject", s.handleAdminRejectUnknown)
+		admin.GET("/safety-incidents", s.handleAdminSafetyIncidents)
+		admin.GET("/question-corrections", s.handleAdminQuestionCorrections)
+	}
+
 	api := r.Group("/api/v1")
 	api.Use(s.withRateLimit())
 	{
 		api.POST("/homework/analyze", s.handleAnalyze)
+		api.POST("/homework/analyze-voice", s.handleAnalyzeVoice)
+		api.POST("/homework/analyze-text", s.handleAnalyzeText)
+		api.POST("/homework/analyze-page", s.handleAnalyzePage)
+		api.POST("/homework/:id/questions/:index/analyze", s.handleAnalyzePageQuestion)
+		api.POST("/homework/:id/crop", s.handleCrop)
 		api.POST("/homework/:id/regenerate", s.handleRegenerate)
+		api.POST("/homework/:id/analyze-text", s.handleReanalyzeText)
+		api.PATCH("/homework/:id/question", s.handleQuestionCorrect)
+		api.GET("/homework/:id/versions", s.handleVersions)
+		api.POST("/homework/:id/versions/:vid/select", s.handleVersionSelect)
+		api.POST("/homework/:id/messages", s.handleHomeworkMessage)
+		api.POST("/homework/:id/speech", s.handleSpeech)
+		api.POST("/homework/:id/rehearsals", s.handleRehearsalStart)
+		api.GET("/homework/:id/rehearsals/:sid", s.handleRehearsalDetail)
+		api.POST("/homework/:id/rehearsals/:sid/turns", s.handleRehearsalTurn)
+		api.POST("/homework/:id/rehearsals/:sid/finish", s.handleRehearsalFinish)
+		api.POST("/homework/:id/practice", s.handlePracticeGenerate)
+		api.GET("/homework/:id/practice", s.handlePracticeList)
+		api.POST("/homework/:id/practice/:pid/answer", s.handlePracticeAnswer)
+		api.PUT("/homework/:id/mistake", s.handleMarkMistake)
+		api.DELETE("/homework/:id/mistake", s.handleUnmarkMistake)
+		api.GET("/mistakes", s.handleMistakes)
+		api.GET("/mistakes/due", s.handleMistakesDue)
+		api.POST("/mistakes/:mid/review", s.handleMistakeReview)
+		api.GET("/preferences", s.handlePreferences)
+		api.PUT("/preferences", s.handlePreferencesUpdate)
+		api.GET("/children", s.handleChildren)
+		api.POST("/children", s.handleChildCreate)
+		api.PUT("/children/:cid", s.handleChildUpdate)
+		api.DELETE("/children/:cid", s.handleChildDelete)
+		api.GET("/reports/weekly", s.handleWeeklyReport)
 		api.GET("/history", s.handleHistory)
 		api.GET("/history/:id", s.handleHistoryDetail)
 	}
@@ -79,29 +178,32 @@ func (s *Server) handleAnalyze(c *gin.Context) {
 	}
 
 	mode := normalizeMode(c.PostForm("mode"))
-	fileHeader, err := c.FormFile("image")
-	if err != nil {
-		s.fail(c, http.StatusBadRequest, 40002, "image file required")
+	child, ok := s.childFromRequest(c, deviceID)
+	if !ok {
 		return
 	}
-	bytes, contentType, imageURL, err := s.readAndSaveUpload(fileHeader)
-	if err != nil {
-		s.fail(c, http.StatusBadRequest, 40003, err.Error())
+	bytes, contentType, imageURL, ok := s.imageFromRequest(c, deviceID)
+	if !ok {
 		return
 	}
 
-	result, err := s.analyze(c, bytes, contentType, mode)
+	result, err := s.analyze(c, s.withLearner(c, child, openai.AnalyzeInput{Image: bytes, ContentType: contentType, Mode: mode}))
 	if err != nil {
-		log.Printf("[ERROR] analyze: %v", err)
-		if errors.Is(err, errOpenAIConfigMissing) {
-			s.fail(c, http.StatusInternalServerError, 50007, err.Error())
-			return
-		}
-		s.fail(c, http.StatusBadGateway, 50001, "analyze failed")
+		s.failAnalyze(c, "analyze", err)
 		return
 	}
 
-	rec, err := s.Store.CreateHomework(c.Request.Context(), deviceID, mode, imageURL, result.QuestionText, result.SuggestedGrade, result)
+	rec, err := s.Store.CreateHomework(c.Request.Context(), store.NewHomework{
+		DeviceID:     deviceID,
+		Mode:         mode,
+		ChildID:      child.ID,
+		Subject:      result.Subject,
+		ImageURL:     imageURL,
+		QuestionText: result.QuestionText,
+		Grade:        result.SuggestedGrade,
+		Result:       result,
+		Meta:         s.resultMeta(),
+	})
 	if err != nil {
 		log.Printf("[ERROR] create homework: %v", err)
 		s.fail(c, http.StatusInternalServerError, 50002, "save record failed")
@@ -139,26 +241,27 @@ func (s *Server) handleRegenerate(c *gin.Context) {
 		s.fail(c, http.StatusInternalServerError, 50003, "query record failed")
 		return
 	}
+	if rec.Kind == store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40008, "page record cannot be regenerated, analyze a question instead")
+		return
+	}
 
-	imgPath := s.localPathFromURL(rec.SourceImage)
-	b, err := os.ReadFile(imgPath)
+	in, err := s.recordInput(c, rec, mode)
 	if err != nil {
 		s.fail(c, http.StatusBadRequest, 40005, "image source missing")
 		return
 	}
-
-	result, err := s.analyze(c, b, "image/jpeg", mode)
+	result, err := s.analyze(c, in)
 	if err != nil {
-		log.Printf("[ERROR] regenerate analyze: %v", err)
-		if errors.Is(err, errOpenAIConfigMissing) {
-			s.fail(c, http.StatusInternalServerError, 50007, err.Error())
-			return
-		}
-		s.fail(c, http.StatusBadGateway, 50001, "analyze failed")
+		s.failAnalyze(c, "regenerate analyze", err)
 		return
 	}
 
-	updated, err := s.Store.UpdateHomeworkResult(c.Request.Context(), id, deviceID, mode, result.QuestionText, result.SuggestedGrade, result)
+	questionText := result.QuestionText
+	if rec.Kind == store.KindVoice || rec.Kind == store.KindText || rec.RecognizedQuestionText != nil {
+		questionText = rec.QuestionText
+	}
+	updated, err := s.Store.UpdateHomeworkResult(c.Request.Context(), id, deviceID, mode, questionText, false, result.SuggestedGrade, result.Subject, result, s.resultMeta())
 	if err != nil {
 		if store.IsNotFound(err) {
 			s.fail(c, http.StatusNotFound, 40401, "record not found")
@@ -172,13 +275,52 @@ func (s *Server) handleRegenerate(c *gin.Context) {
 	s.success(c, gin.H{"record": toHomeworkResp(updated)})
 }
 
+// recordInput builds the analysis input that re-runs a record from its source:
+// voice records from the stored transcript, text records from their text, and
+// photo records from the stored image, with a corrected question text sent along
+// as authoritative. The error is set when the image can no longer be read.
+func (s *Server) recordInput(c *gin.Context, rec store.HomeworkRecord, mode string) (openai.AnalyzeInput, error) {
+	in := openai.AnalyzeInput{Mode: mode}
+	switch rec.Kind {
+	case store.KindVoice:
+		// Voice records are re-run from the stored transcript, not the recording.
+		in.Transcript = rec.QuestionText
+	case store.KindText:
+		in.Text = rec.QuestionText
+	default:
+		b, contentType, err := s.readStoredImage(rec.SourceImage)
+		if err != nil {
+			return openai.AnalyzeInput{}, err
+		}
+		in.Image, in.ContentType = b, contentType
+		if rec.RecognizedQuestionText != nil {
+			in.CorrectedText = rec.QuestionText
+		}
+	}
+	in = s.withLearner(c, s.recordChild(c, rec), in)
+	if rec.Kind == store.KindQuestion {
+		in.Focus = rec.QuestionText
+	}
+	in.Subject = rec.Subject
+	return in, nil
+}
+
 func (s *Server) handleHistory(c *gin.Context) {
 	deviceID := deviceIDFromRequest(c)
 	if deviceID == "" {
 		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
 		return
 	}
-	items, err := s.Store.ListHistoryByDevice(c.Request.Context(), deviceID, 100)
+	var childID int64
+	if raw := strings.TrimSpace(c.Query("child_id")); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			s.fail(c, http.StatusBadRequest, 40023, "invalid child id")
+			return
+		}
+		childID = id
+	}
+	items, err := s.Store.ListHistoryByDevice(c.Request.Context(), deviceID, childID, 100)
 	if err != nil {
 		log.Printf("[ERROR] list history: %v", err)
 		s.fail(c, http.StatusInternalServerError, 50005, "query history failed")
@@ -210,44 +352,228 @@ func (s *Server) handleHistoryDetail(c *gin.Context) {
 		return
 	}
 
-	s.success(c, gin.H{"record": toHomeworkResp(rec)})
+	resp := toHomeworkResp(rec)
+	if rec.Kind == store.KindPage {
+		children, err := s.Store.ListChildHomework(c.Request.Context(), rec.ID, deviceID)
+		if err != nil {
+			log.Printf("[ERROR] list page children: %v", err)
+			s.fail(c, http.StatusInternalServerError, 50006, "query detail failed")
+			return
+		}
+		resp.Children = children
+	}
+	messages, err := s.Store.ListHomeworkMessages(c.Request.Context(), rec.ID)
+	if err != nil {
+		log.Printf("[ERROR] list homework messages: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50006, "query detail failed")
+		return
+	}
+	resp.Messages = messages
+	if m, err := s.Store.GetMistakeByRecord(c.Request.Context(), rec.ID, deviceID); err == nil {
+		resp.Mistake = &m
+	} else if !store.IsNotFound(err) {
+		log.Printf("[ERROR] get record mistake: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50006, "query detail failed")
+		return
+	}
+	s.success(c, gin.H{"record": resp})
 }
 
-func (s *Server) analyze(c *gin.Context, imageBytes []byte, contentType string, mode string) (openai.AnalyzeResult, error) {
+// loadRecord resolves the `:id` record owned by the requesting device, writing the
+// failure response itself when it cannot.
+func (s *Server) loadRecord(c *gin.Context) (store.HomeworkRecord, bool) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return store.HomeworkRecord{}, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		s.fail(c, http.StatusBadRequest, 40004, "invalid id")
+		return store.HomeworkRecord{}, false
+	}
+	rec, err := s.Store.GetHomeworkByIDAndDevice(c.Request.Context(), id, deviceID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40401, "record not found")
+			return store.HomeworkRecord{}, false
+		}
+		log.Printf("[ERROR] get homework: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50003, "query record failed")
+		return store.HomeworkRecord{}, false
+	}
+	return rec, true
+}
+
+// analyze classifies the subject unless the request or caller fixed it, then runs
+// the subject-specific analysis with the edition's vocabulary for that subject.
+func (s *Server) analyze(c *gin.Context, in openai.AnalyzeInput) (openai.AnalyzeResult, error) {
+	if subject := openai.NormalizeSubject(requestValue(c, "subject")); subject != "" {
+		in.Subject = subject
+	}
+	in.Language = s.language(c)
 	if s.AnalyzeMock {
-		return mockResult(mode), nil
+		result := mockResult(in.Mode, in.Language)
+		if in.Grade != "" {
+			result.SuggestedGrade = in.Grade
+		}
+		return result, nil
 	}
 	if s.OpenAI == nil || strings.TrimSpace(s.OpenAI.APIKey) == "" {
 		return openai.AnalyzeResult{}, errOpenAIConfigMissing
 	}
-	return s.OpenAI.AnalyzeHomework(c.Request.Context(), imageBytes, contentType, mode)
+	if openai.NormalizeSubject(in.Subject) == "" {
+		focus := in.Focus
+		if len(in.Image) == 0 {
+			focus = firstNonEmpty(in.Transcript, in.Text)
+		}
+		subject, err := s.OpenAI.ClassifySubject(c.Request.Context(), in.Image, in.ContentType, focus, in.Language)
+		if err != nil {
+			log.Printf("[WARN] classify subject: %v", err)
+			subject = openai.SubjectOther
+		}
+		in.Subject = subject
+	}
+	in.Vocabulary = s.Curriculum.Points(in.Edition, in.Grade, in.Subject)
+	result, err := s.OpenAI.Analyze(c.Request.Context(), in)
+	if err != nil {
+		return openai.AnalyzeResult{}, err
+	}
+	if len(result.SafetyFindings) > 0 {
+		s.recordSafetyIncident(c, store.SafetyRegenerated, result.SafetyFindings)
+	}
+	if result.Unreadable() {
+		return openai.AnalyzeResult{}, &unreadableError{result: result}
+	}
+	return result, nil
+}
+
+// failAnalyze maps a model call error to the shared analyze error responses. An
+// unreadable photo is the user's retake, not a failure: it gets its own 422 with
+// suggestions and its rate-limit token back.
+func (s *Server) failAnalyze(c *gin.Context, tag string, err error) {
+	var unreadable *unreadableError
+	if errors.As(err, &unreadable) {
+		log.Printf("[INFO] %s: %v", tag, err)
+		if s.Limiter != nil {
+			s.Limiter.Refund(deviceIDFromRequest(c))
+		}
+		msg := unreadable.message
+		if msg == "" {
+			msg = "question unreadable, please retake the photo"
+		}
+		c.JSON(http.StatusUnprocessableEntity, apiResp{
+			Code:    42202,
+			Message: s.localize(c, msg),
+			Data: gin.H{
+				"legibility":  unreadable.result.Legibility,
+				"confidence":  unreadable.result.Confidence,
+				"suggestions": unreadable.result.RetakeTips,
+			},
+		})
+		return
+	}
+	var unsafe *openai.UnsafeError
+	if errors.As(err, &unsafe) {
+		log.Printf("[WARN] %s: %v", tag, err)
+		s.recordSafetyIncident(c, store.SafetyBlocked, unsafe.Findings)
+		if s.Limiter != nil {
+			s.Limiter.Refund(deviceIDFromRequest(c))
+		}
+		s.fail(c, http.StatusUnprocessableEntity, 42203, "analysis withheld by safety check, please try again")
+		return
+	}
+	log.Printf("[ERROR] %s: %v", tag, err)
+	var invalid *openai.ValidationError
+	if errors.As(err, &invalid) {
+		s.fail(c, http.StatusBadGateway, 50027, "analyze output invalid")
+		return
+	}
+	if errors.Is(err, errOpenAIConfigMissing) {
+		s.fail(c, http.StatusInternalServerError, 50007, err.Error())
+		return
+	}
+	s.fail(c, http.StatusBadGateway, 50001, "analyze failed")
+}
+
+// recordSafetyIncident logs an unsafe result; a failed write only loses the log entry.
+func (s *Server) recordSafetyIncident(c *gin.Context, action string, findings []safety.Finding) {
+	if err := s.Store.CreateSafetyIncident(c.Request.Context(), deviceIDFromRequest(c), action, findings); err != nil {
+		log.Printf("[ERROR] save safety incident: %v", err)
+	}
 }
 
 func (s *Server) readAndSaveUpload(file *multipart.FileHeader) ([]byte, string, string, error) {
+	limits := s.UploadLimits
+	if limits.MaxBytes <= 0 {
+		limits.MaxBytes = media.DefaultMaxBytes
+	}
+	if file.Size > limits.MaxBytes {
+		return nil, "", "", media.ErrTooLarge
+	}
 	src, err := file.Open()
 	if err != nil {
 		return nil, "", "", err
 	}
 	defer src.Close()
 
-	b, err := io.ReadAll(io.LimitReader(src, 8*1024*1024))
+	b, err := media.ReadLimited(src, limits.MaxBytes)
+	if err != nil {
+		return nil, "", "", err
+	}
+	img, err := media.ValidateImage(b, limits)
 	if err != nil {
 		return nil, "", "", err
 	}
-	if len(b) == 0 {
-		return nil, "", "", errors.New("empty file")
+	imageURL, err := s.saveUpload(img.Bytes, img.Ext)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return img.Bytes, img.ContentType, imageURL, nil
+}
+
+// failUpload maps upload validation errors to responses: oversized files get 413,
+// HEIC gets 415 so the client can suggest converting, everything else is a 400.
+func (s *Server) failUpload(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		s.fail(c, http.StatusRequestEntityTooLarge, 41301, fmt.Sprintf("image too large, max %dMB", s.maxUploadBytes()>>20))
+	case errors.Is(err, media.ErrHEIC):
+		s.fail(c, http.StatusUnsupportedMediaType, 41501, err.Error())
+	default:
+		s.fail(c, http.StatusBadRequest, 40003, err.Error())
+	}
+}
+
+func (s *Server) maxUploadBytes() int64 {
+	if s.UploadLimits.MaxBytes > 0 {
+		return s.UploadLimits.MaxBytes
 	}
-	contentType := http.DetectContentType(b)
-	if !strings.HasPrefix(contentType, "image/") {
-		return nil, "", "", errors.New("only image is supported")
+	return media.DefaultMaxBytes
+}
+
+// readStoredImage loads a previously saved upload and its content type.
+func (s *Server) readStoredImage(imageURL string) ([]byte, string, error) {
+	path := s.localPathFromURL(imageURL)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
 	}
-	ext := extByContentType(contentType)
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "image/jpeg"
+	}
+	return b, contentType, nil
+}
+
+// saveUpload writes b under UploadDir with a fresh name and returns its public URL.
+func (s *Server) saveUpload(b []byte, ext string) (string, error) {
 	name := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
 	fullPath := filepath.Join(s.UploadDir, name)
 	if err := os.WriteFile(fullPath, b, 0o644); err != nil {
-		return nil, "", "", err
+		return "", err
 	}
-	return b, contentType, "/uploads/" + name, nil
+	return "/uploads/" + name, nil
 }
 
 func (s *Server) localPathFromURL(imageURL string) string {
@@ -289,8 +615,8 @@ func (s *Server) requestLogger() gin.HandlerFunc {
 func (s *Server) cors() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Device-Id")
-		c.Header("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Device-Id, Upload-Offset")
+		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
 		if c.Request.Method == http.MethodOptions {
 			c.AbortWithStatus(http.StatusNoContent)
 			return
@@ -304,7 +630,7 @@ func (s *Server) success(c *gin.Context, data any) {
 }
 
 func (s *Server) fail(c *gin.Context, status int, code int, message string) {
-	c.JSON(status, apiResp{Code: code, Message: message})
+	c.JSON(status, apiResp{Code: code, Message: s.localize(c, message)})
 }
 
 func deviceIDFromRequest(c *gin.Context) string {
@@ -318,17 +644,6 @@ func deviceIDFromRequest(c *gin.Context) string {
 	return deviceID
 }
 
-func extByContentType(contentType string) string {
-	switch contentType {
-	case "image/png":
-		return ".png"
-	case "image/webp":
-		return ".webp"
-	default:
-		return ".jpg"
-	}
-}
-
 func normalizeMode(mode string) string {
 	v := strings.TrimSpace(strings.ToLower(mode))
 	switch v {
@@ -344,41 +659,127 @@ func toHomeworkResp(rec store.HomeworkRecord) homeworkResp {
 	if len(rec.ResultJSONRaw) > 0 {
 		_ = json.Unmarshal(rec.ResultJSONRaw, &parsed)
 	}
+	var questions []openai.DetectedQuestion
+	if len(rec.PageQuestions) > 0 {
+		_ = json.Unmarshal(rec.PageQuestions, &questions)
+	}
+	var region *openai.BoundingBox
+	if len(rec.Region) > 0 {
+		region = &openai.BoundingBox{}
+		if err := json.Unmarshal(rec.Region, region); err != nil {
+			region = nil
+		}
+	}
+	var audio *recordAudio
+	if len(rec.Audio) > 0 && string(rec.Audio) != "null" {
+		audio = &recordAudio{}
+		if err := json.Unmarshal(rec.Audio, audio); err != nil {
+			audio = nil
+		}
+	}
 	return homeworkResp{
-		ID:             rec.ID,
-		Mode:           rec.Mode,
-		SourceImage:    rec.SourceImage,
-		QuestionText:   rec.QuestionText,
-		SuggestedGrade: rec.Grade,
-		Result:         parsed,
-		SolvedAt:       rec.SolvedAt,
-	}
-}
-
-func mockResult(mode string) openai.AnalyzeResult {
-	prefix := "引导思考"
-	switch mode {
-	case "detailed":
-		prefix = "详细讲解"
-	case "noanswer":
-		prefix = "不给答案"
-	case "quick":
-		prefix = "快速提示"
-	}
-	return openai.AnalyzeResult{
-		QuestionText:     "24 × 15 = ?",
-		SolutionThoughts: prefix + "：把 15 拆成 10 和 5，分别与 24 相乘后相加，过程比答案更重要。",
-		ExplainToChild:   "我们先算 24×10，再算 24×5，最后把两个结果加起来。",
-		ParentGuidance: []string{
+		ID:                     rec.ID,
+		Kind:                   rec.Kind,
+		ParentID:               rec.ParentID,
+		ChildID:                rec.ChildID,
+		Mode:                   rec.Mode,
+		SourceImage:            rec.SourceImage,
+		QuestionText:           rec.QuestionText,
+		SuggestedGrade:         rec.Grade,
+		Result:                 parsed,
+		Questions:              questions,
+		Region:                 region,
+		Audio:                  audio,
+		RecognizedQuestionText: rec.RecognizedQuestionText,
+		CurrentVersionID:       rec.CurrentVersionID,
+		SolvedAt:               rec.SolvedAt,
+	}
+}
+
+// mockText is the language-dependent text of the mock analysis.
+type mockText struct {
+	modeLabels       map[string]string
+	solutionThoughts string
+	explainToChild   string
+	parentGuidance   []string
+	childStuckPoints []string
+	suggestedGrade   string
+}
+
+var mockTexts = map[string]mockText{
+	openai.LanguageZhCN: {
+		modeLabels:       map[string]string{"guided": "引导思考", "detailed": "详细讲解", "noanswer": "不给答案", "quick": "快速提示"},
+		solutionThoughts: "：把 15 拆成 10 和 5，分别与 24 相乘后相加，过程比答案更重要。",
+		explainToChild:   "我们先算 24×10，再算 24×5，最后把两个结果加起来。",
+		parentGuidance: []string{
 			"你先说说为什么可以把 15 拆成 10 和 5？",
 			"如果先算 24×5，你会怎么口算？",
 			"两部分结果加起来前，先估一估答案大概是多少？",
 		},
-		ChildStuckPoints: []string{
+		childStuckPoints: []string{
 			"容易忘记把两部分乘积相加。",
 			"对两位数乘法拆分不熟悉。",
 		},
-		KnowledgePoints: []string{"两位数乘法", "乘法分配律", "口算与估算"},
-		SuggestedGrade:  "三年级",
+		suggestedGrade: "三年级",
+	},
+	openai.LanguageZhTW: {
+		modeLabels:       map[string]string{"guided": "引導思考", "detailed": "詳細講解", "noanswer": "不給答案", "quick": "快速提示"},
+		solutionThoughts: "：把 15 拆成 10 和 5，分別與 24 相乘後相加，過程比答案更重要。",
+		explainToChild:   "我們先算 24×10，再算 24×5，最後把兩個結果加起來。",
+		parentGuidance: []string{
+			"你先說說為什麼可以把 15 拆成 10 和 5？",
+			"如果先算 24×5，你會怎麼心算？",
+			"兩部分結果加起來前，先估一估答案大概是多少？",
+		},
+		childStuckPoints: []string{
+			"容易忘記把兩部分乘積相加。",
+			"對兩位數乘法拆分不熟悉。",
+		},
+		suggestedGrade: "三年級",
+	},
+	openai.LanguageEN: {
+		modeLabels:       map[string]string{"guided": "Guided thinking", "detailed": "Detailed walkthrough", "noanswer": "No answer", "quick": "Quick hint"},
+		solutionThoughts: ": split 15 into 10 and 5 (拆分), multiply each by 24 and add the results; the process matters more than the answer.",
+		explainToChild:   "First work out 24×10, then 24×5, and finally add the two results together.",
+		parentGuidance: []string{
+			"Can you tell me why we can split 15 into 10 and 5?",
+			"If you start with 24×5, how would you work it out in your head?",
+			"Before adding the two parts, can you estimate roughly what the answer is?",
+		},
+		childStuckPoints: []string{
+			"Forgetting to add the two partial products.",
+			"Not yet comfortable splitting a two-digit number to multiply.",
+		},
+		suggestedGrade: "Grade 3",
+	},
+}
+
+func mockResult(mode, lang string) openai.AnalyzeResult {
+	text, ok := mockTexts[openai.NormalizeLanguage(lang)]
+	if !ok {
+		text = mockTexts[openai.DefaultLanguage]
+	}
+	label, ok := text.modeLabels[mode]
+	if !ok {
+		label = text.modeLabels["guided"]
 	}
+	// The mock goes through the same answer hiding as model results.
+	return openai.HideAnswer(openai.AnalyzeResult{
+		QuestionText:     "24 × 15 = ?",
+		SolutionThoughts: label + text.solutionThoughts,
+		ExplainToChild:   text.explainToChild,
+		ParentGuidance:   text.parentGuidance,
+		ChildStuckPoints: text.childStuckPoints,
+		KnowledgePoints:  []string{"两位数乘法", "乘法分配律", "口算与估算"},
+		SuggestedGrade:   text.suggestedGrade,
+		Subject:          openai.SubjectMath,
+		Legibility:       openai.LegibilityClear,
+		Confidence:       0.95,
+		Math: &openai.MathDetails{
+			Steps:       []string{"24 × 10 = 240", "24 × 5 = 120", "240 + 120 = 360"},
+			FinalAnswer: "360",
+			Expression:  "24*15",
+		},
+		Verification: &openai.Verification{Status: openai.VerifyPassed, Expression: "24*15"},
+	}, mode)
 }
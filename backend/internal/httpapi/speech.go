@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// SpeechCacheDir is the sub-directory of the upload dir holding synthesized audio,
+// named by a hash of voice and text so identical lines are synthesized once.
+const SpeechCacheDir = "tts"
+
+var (
+	errSpeechConfigMissing = errors.New("speech not configured: set TTS_API_KEY or OPENAI_API_KEY")
+	voicePattern           = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+)
+
+// recordAudio holds the audio URLs of a record's child-facing text; ParentGuidance
+// follows the order of result.parent_guidance.
+type recordAudio struct {
+	Voice          string   `json:"voice"`
+	ExplainToChild string   `json:"explainToChild,omitempty"`
+	ParentGuidance []string `json:"parentGuidance"`
+}
+
+type speechReq struct {
+	Voice string `json:"voice" form:"voice"`
+}
+
+// handleSpeech reads explain_to_child and each parent_guidance line aloud and
+// stores the audio URLs on the record.
+func (s *Server) handleSpeech(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	if rec.Kind == store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40008, "page record has no analysis, analyze a question instead")
+		return
+	}
+	var req speechReq
+	_ = c.ShouldBind(&req)
+	voice := strings.ToLower(strings.TrimSpace(req.Voice))
+	if voice != "" && !voicePattern.MatchString(voice) {
+		s.fail(c, http.StatusBadRequest, 40029, "invalid voice")
+		return
+	}
+	if s.Speaker == nil || strings.TrimSpace(s.Speaker.APIKey) == "" {
+		log.Printf("[ERROR] speech: %v", errSpeechConfigMissing)
+		s.fail(c, http.StatusInternalServerError, 50007, errSpeechConfigMissing.Error())
+		return
+	}
+	if voice == "" {
+		voice = s.Speaker.Voice
+	}
+
+	var result openai.AnalyzeResult
+	_ = json.Unmarshal(rec.ResultJSONRaw, &result)
+	audio := recordAudio{Voice: voice, ParentGuidance: make([]string, 0, len(result.ParentGuidance))}
+	var err error
+	if strings.TrimSpace(result.ExplainToChild) != "" {
+		if audio.ExplainToChild, err = s.speechURL(c, result.ExplainToChild, voice); err != nil {
+			s.failSpeech(c, err)
+			return
+		}
+	}
+	for _, line := range result.ParentGuidance {
+		url := ""
+		if strings.TrimSpace(line) != "" {
+			if url, err = s.speechURL(c, line, voice); err != nil {
+				s.failSpeech(c, err)
+				return
+			}
+		}
+		audio.ParentGuidance = append(audio.ParentGuidance, url)
+	}
+	if err := s.Store.SetHomeworkAudio(c.Request.Context(), rec.ID, rec.DeviceID, audio); err != nil {
+		log.Printf("[ERROR] save homework audio: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50004, "update record failed")
+		return
+	}
+	s.success(c, gin.H{"audio": audio})
+}
+
+// speechURL returns the cached audio of text, synthesizing it on a miss.
+func (s *Server) speechURL(c *gin.Context, text, voice string) (string, error) {
+	sum := sha256.Sum256([]byte(voice + "\x00" + strings.TrimSpace(text)))
+	name := hex.EncodeToString(sum[:]) + ".mp3"
+	dir := filepath.Join(s.UploadDir, SpeechCacheDir)
+	path := filepath.Join(dir, name)
+	url := "/uploads/" + SpeechCacheDir + "/" + name
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		// The janitor prunes by modification time, so a hit marks the file used.
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		return url, nil
+	}
+	b, err := s.Speaker.Speak(c.Request.Context(), text, voice)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	// Write then rename so a concurrent request never serves a partial file.
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	return url, os.Rename(tmp.Name(), path)
+}
+
+func (s *Server) failSpeech(c *gin.Context, err error) {
+	log.Printf("[ERROR] speech: %v", err)
+	s.fail(c, http.StatusBadGateway, 50030, "synthesize speech failed")
+}
@@ -0,0 +1,149 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// maxQuestionTextRunes bounds a typed question.
+const maxQuestionTextRunes = 1000
+
+// handleAnalyzeText analyzes a typed question with the same modes and schema as a
+// photo, without an image part.
+func (s *Server) handleAnalyzeText(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	text, ok := s.questionTextFromRequest(c)
+	if !ok {
+		return
+	}
+	mode := normalizeMode(c.PostForm("mode"))
+	child, ok := s.childFromRequest(c, deviceID)
+	if !ok {
+		return
+	}
+
+	result, err := s.analyze(c, s.withLearner(c, child, openai.AnalyzeInput{Mode: mode, Text: text}))
+	if err != nil {
+		s.failAnalyze(c, "analyze text", err)
+		return
+	}
+
+	rec, err := s.Store.CreateHomework(c.Request.Context(), store.NewHomework{
+		DeviceID:     deviceID,
+		Mode:         mode,
+		Kind:         store.KindText,
+		ChildID:      child.ID,
+		Subject:      result.Subject,
+		QuestionText: text,
+		Grade:        result.SuggestedGrade,
+		Result:       result,
+		Meta:         s.resultMeta(),
+	})
+	if err != nil {
+		log.Printf("[ERROR] create homework: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50002, "save record failed")
+		return
+	}
+
+	s.success(c, gin.H{"record": toHomeworkResp(rec)})
+}
+
+// handleReanalyzeText replaces a record's question text with the parent's typed
+// correction and re-analyzes the record with it.
+func (s *Server) handleReanalyzeText(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	if rec.Kind == store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40008, "page record has no analysis, analyze a question instead")
+		return
+	}
+	text, ok := s.questionTextFromRequest(c)
+	if !ok {
+		return
+	}
+	mode := normalizeMode(firstNonEmpty(c.PostForm("mode"), rec.Mode))
+	// The correction is saved together with the new result, so a failed analysis
+	// leaves the record as it was.
+	corrected := text != rec.QuestionText
+	next := rec
+	next.QuestionText = text
+
+	// Photo records keep their image, with the typed text as authoritative, the
+	// same as correcting the question and regenerating. A photo removed by
+	// retention leaves the text alone.
+	in, err := s.recordInput(c, next, mode)
+	if err != nil {
+		in = s.withLearner(c, s.recordChild(c, rec), openai.AnalyzeInput{Mode: mode, Text: text})
+		in.Subject = rec.Subject
+	}
+	if len(in.Image) > 0 {
+		in.CorrectedText = text
+	}
+	result, err := s.analyze(c, in)
+	if err != nil {
+		s.failAnalyze(c, "reanalyze text", err)
+		return
+	}
+	updated, err := s.Store.UpdateHomeworkResult(c.Request.Context(), rec.ID, rec.DeviceID, mode, text, corrected, result.SuggestedGrade, result.Subject, result, s.resultMeta())
+	if err != nil {
+		log.Printf("[ERROR] update homework: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50004, "update record failed")
+		return
+	}
+	s.success(c, gin.H{"record": toHomeworkResp(updated)})
+}
+
+// handleQuestionCorrect saves the parent's fix of the recognized question text.
+// The original recognition is kept; regenerating then sends the image together
+// with the corrected text as authoritative.
+func (s *Server) handleQuestionCorrect(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	if rec.Kind == store.KindPage {
+		s.fail(c, http.StatusBadRequest, 40008, "page record has no analysis, analyze a question instead")
+		return
+	}
+	text, ok := s.questionTextFromRequest(c)
+	if !ok {
+		return
+	}
+	if text != rec.QuestionText {
+		if rec, ok = s.correctQuestion(c, rec, text); !ok {
+			return
+		}
+	}
+	s.success(c, gin.H{"record": toHomeworkResp(rec)})
+}
+
+func (s *Server) correctQuestion(c *gin.Context, rec store.HomeworkRecord, text string) (store.HomeworkRecord, bool) {
+	updated, err := s.Store.CorrectQuestionText(c.Request.Context(), rec.ID, rec.DeviceID, text)
+	if err != nil {
+		log.Printf("[ERROR] correct question text: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50004, "update record failed")
+		return store.HomeworkRecord{}, false
+	}
+	return updated, true
+}
+
+func (s *Server) questionTextFromRequest(c *gin.Context) (string, bool) {
+	text := requestValue(c, "question_text")
+	if text == "" || utf8.RuneCountInString(text) > maxQuestionTextRunes {
+		s.fail(c, http.StatusBadRequest, 40031, "question text required, at most 1000 characters")
+		return "", false
+	}
+	return text, true
+}
@@ -0,0 +1,347 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/media"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+const (
+	// uploadChunkSize is the chunk size suggested to clients; uploadMaxChunk is the
+	// most a single PUT may carry.
+	uploadChunkSize = 256 * 1024
+	uploadMaxChunk  = 1024 * 1024
+	uploadTTL       = 24 * time.Hour
+
+	// PartialUploadDir is the sub-directory of UploadDir holding in-progress chunked
+	// uploads, one file per session id.
+	PartialUploadDir = ".partial"
+)
+
+type uploadInitReq struct {
+	Size int64 `json:"size" form:"size"`
+}
+
+// handleUploadInit opens a chunked upload session for a file of the declared size.
+func (s *Server) handleUploadInit(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+	var req uploadInitReq
+	if err := c.ShouldBind(&req); err != nil || req.Size <= 0 {
+		s.fail(c, http.StatusBadRequest, 40011, "upload size required")
+		return
+	}
+	if req.Size > s.maxUploadBytes() {
+		s.failUpload(c, media.ErrTooLarge)
+		return
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		log.Printf("[ERROR] new upload id: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50009, "create upload failed")
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(s.UploadDir, PartialUploadDir), 0o755); err != nil {
+		log.Printf("[ERROR] create partial dir: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50009, "create upload failed")
+		return
+	}
+	if err := os.WriteFile(s.partialPath(id), nil, 0o644); err != nil {
+		log.Printf("[ERROR] create partial file: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50009, "create upload failed")
+		return
+	}
+	sess, err := s.Store.CreateUploadSession(c.Request.Context(), id, deviceID, req.Size, time.Now().Add(uploadTTL))
+	if err != nil {
+		log.Printf("[ERROR] create upload session: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50009, "create upload failed")
+		return
+	}
+
+	s.success(c, gin.H{"upload": sess, "chunkSize": uploadChunkSize})
+}
+
+// handleUploadStatus lets a client resume by asking for the next expected offset.
+func (s *Server) handleUploadStatus(c *gin.Context) {
+	sess, ok := s.loadUploadSession(c)
+	if !ok {
+		return
+	}
+	s.success(c, gin.H{"upload": sess, "chunkSize": uploadChunkSize})
+}
+
+// handleUploadChunk appends one chunk. The offset (query `offset` or header
+// `Upload-Offset`) must equal the bytes received so far; on mismatch the current
+// offset is returned with 409 so the client can resume from there.
+func (s *Server) handleUploadChunk(c *gin.Context) {
+	rawOffset := c.Query("offset")
+	if rawOffset == "" {
+		rawOffset = c.GetHeader("Upload-Offset")
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(rawOffset), 10, 64)
+	if err != nil || offset < 0 {
+		s.fail(c, http.StatusBadRequest, 40012, "invalid offset")
+		return
+	}
+
+	chunk, err := media.ReadLimited(c.Request.Body, uploadMaxChunk)
+	if err != nil {
+		if errors.Is(err, media.ErrTooLarge) {
+			s.fail(c, http.StatusRequestEntityTooLarge, 41302, "chunk too large")
+			return
+		}
+		s.fail(c, http.StatusBadRequest, 40013, "read chunk failed")
+		return
+	}
+	if len(chunk) == 0 {
+		s.fail(c, http.StatusBadRequest, 40013, "empty chunk")
+		return
+	}
+
+	// The partial file is truncated and written before the offset is advanced, so
+	// requests for one session must not interleave. The session is loaded under
+	// the lock to see the offset the previous writer left.
+	unlock := s.uploadLocks.lock(c.Param("uploadId"))
+	defer unlock()
+	sess, ok := s.loadUploadSession(c)
+	if !ok {
+		return
+	}
+	if sess.Status != store.UploadPending {
+		s.fail(c, http.StatusConflict, 40902, "upload already completed")
+		return
+	}
+	if offset != sess.ReceivedSize {
+		s.offsetMismatch(c, sess.ReceivedSize)
+		return
+	}
+	if offset+int64(len(chunk)) > sess.TotalSize {
+		s.fail(c, http.StatusBadRequest, 40014, "chunk exceeds declared size")
+		return
+	}
+
+	if err := writeChunk(s.partialPath(sess.ID), offset, chunk); err != nil {
+		log.Printf("[ERROR] write chunk: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50010, "write chunk failed")
+		return
+	}
+	updated, err := s.Store.AdvanceUploadSession(c.Request.Context(), sess.ID, sess.DeviceID, offset, offset+int64(len(chunk)))
+	if err != nil {
+		if store.IsNotFound(err) {
+			// Another request for the same offset won the race.
+			current, gerr := s.Store.GetUploadSession(c.Request.Context(), sess.ID, sess.DeviceID)
+			if gerr == nil {
+				s.offsetMismatch(c, current.ReceivedSize)
+				return
+			}
+		}
+		log.Printf("[ERROR] advance upload session: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50010, "write chunk failed")
+		return
+	}
+
+	s.success(c, gin.H{"upload": updated})
+}
+
+// handleUploadComplete validates the assembled file like a direct upload and turns
+// it into a blob the analyze endpoints accept via `upload_id`.
+func (s *Server) handleUploadComplete(c *gin.Context) {
+	unlock := s.uploadLocks.lock(c.Param("uploadId"))
+	defer unlock()
+	sess, ok := s.loadUploadSession(c)
+	if !ok {
+		return
+	}
+	if sess.Status == store.UploadCompleted {
+		s.success(c, gin.H{"upload": sess})
+		return
+	}
+	if sess.ReceivedSize != sess.TotalSize {
+		s.offsetMismatch(c, sess.ReceivedSize)
+		return
+	}
+
+	partial := s.partialPath(sess.ID)
+	b, err := os.ReadFile(partial)
+	if err != nil {
+		log.Printf("[ERROR] read partial upload: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50011, "complete upload failed")
+		return
+	}
+	if int64(len(b)) != sess.TotalSize {
+		s.fail(c, http.StatusConflict, 40903, "upload data incomplete")
+		return
+	}
+	img, err := media.ValidateImage(b, s.UploadLimits)
+	if err != nil {
+		s.failUpload(c, err)
+		return
+	}
+	blobURL, err := s.saveUpload(img.Bytes, img.Ext)
+	if err != nil {
+		log.Printf("[ERROR] save upload blob: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50011, "complete upload failed")
+		return
+	}
+	done, err := s.Store.CompleteUploadSession(c.Request.Context(), sess.ID, sess.DeviceID, blobURL, img.ContentType)
+	if err != nil {
+		log.Printf("[ERROR] complete upload session: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50011, "complete upload failed")
+		return
+	}
+	_ = os.Remove(partial)
+
+	s.success(c, gin.H{"upload": done})
+}
+
+func (s *Server) loadUploadSession(c *gin.Context) (store.UploadSession, bool) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return store.UploadSession{}, false
+	}
+	sess, err := s.Store.GetUploadSession(c.Request.Context(), c.Param("uploadId"), deviceID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40403, "upload not found or expired")
+			return store.UploadSession{}, false
+		}
+		log.Printf("[ERROR] get upload session: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50012, "query upload failed")
+		return store.UploadSession{}, false
+	}
+	return sess, true
+}
+
+// readUploadedBlob resolves a completed upload session into the stored image.
+func (s *Server) readUploadedBlob(c *gin.Context, deviceID, uploadID string) ([]byte, string, string, bool) {
+	sess, err := s.Store.GetUploadSession(c.Request.Context(), uploadID, deviceID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40403, "upload not found or expired")
+			return nil, "", "", false
+		}
+		log.Printf("[ERROR] get upload session: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50012, "query upload failed")
+		return nil, "", "", false
+	}
+	if sess.Status != store.UploadCompleted {
+		s.fail(c, http.StatusConflict, 40904, "upload not completed")
+		return nil, "", "", false
+	}
+	b, _, err := s.readStoredImage(sess.BlobURL)
+	if err != nil {
+		s.fail(c, http.StatusBadRequest, 40005, "image source missing")
+		return nil, "", "", false
+	}
+	return b, sess.ContentType, sess.BlobURL, true
+}
+
+// imageFromRequest takes the image either from the multipart `image` file or from a
+// completed chunked upload referenced by `upload_id`, writing the failure response
+// itself when neither works.
+func (s *Server) imageFromRequest(c *gin.Context, deviceID string) ([]byte, string, string, bool) {
+	if uploadID := strings.TrimSpace(c.PostForm("upload_id")); uploadID != "" {
+		return s.readUploadedBlob(c, deviceID, uploadID)
+	}
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		s.fail(c, http.StatusBadRequest, 40002, "image file required")
+		return nil, "", "", false
+	}
+	b, contentType, imageURL, err := s.readAndSaveUpload(fileHeader)
+	if err != nil {
+		s.failUpload(c, err)
+		return nil, "", "", false
+	}
+	return b, contentType, imageURL, true
+}
+
+func (s *Server) offsetMismatch(c *gin.Context, offset int64) {
+	c.JSON(http.StatusConflict, apiResp{Code: 40901, Message: "offset mismatch", Data: gin.H{"offset": offset}})
+}
+
+func (s *Server) partialPath(id string) string {
+	return filepath.Join(s.UploadDir, PartialUploadDir, filepath.Base(id))
+}
+
+// writeChunk drops any bytes past offset left by an earlier failed attempt, then
+// appends the chunk.
+func writeChunk(path string, offset int64, chunk []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = f.Write(chunk)
+	return err
+}
+
+// keyedMutex serializes work per key, e.g. chunk writes of one upload session.
+// The zero value is ready to use; idle keys are dropped.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// lock blocks until key is free and returns the function that releases it.
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	l := k.locks[key]
+	if l == nil {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.waiters++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		if l.waiters--; l.waiters == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
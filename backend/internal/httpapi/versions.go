@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// resultMeta describes the model and prompt behind a new result version.
+func (s *Server) resultMeta() store.ResultMeta {
+	model := "mock"
+	if !s.AnalyzeMock && s.OpenAI != nil {
+		model = s.OpenAI.Model
+	}
+	return store.ResultMeta{Model: model, PromptVersion: openai.PromptVersion}
+}
+
+// handleVersions lists every generated result of a record, newest first.
+func (s *Server) handleVersions(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	versions, err := s.Store.ListResultVersions(c.Request.Context(), rec.ID, rec.DeviceID)
+	if err != nil {
+		log.Printf("[ERROR] list result versions: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50032, "query versions failed")
+		return
+	}
+	s.success(c, gin.H{"currentVersionId": rec.CurrentVersionID, "versions": versions})
+}
+
+// handleVersionSelect makes an earlier version the record's shown result without
+// generating anything.
+func (s *Server) handleVersionSelect(c *gin.Context) {
+	rec, ok := s.loadRecord(c)
+	if !ok {
+		return
+	}
+	vid, err := strconv.ParseInt(c.Param("vid"), 10, 64)
+	if err != nil || vid <= 0 {
+		s.fail(c, http.StatusBadRequest, 40032, "invalid version id")
+		return
+	}
+	updated, err := s.Store.SelectResultVersion(c.Request.Context(), rec.ID, rec.DeviceID, vid)
+	if err != nil {
+		if store.IsNotFound(err) {
+			s.fail(c, http.StatusNotFound, 40409, "version not found")
+			return
+		}
+		log.Printf("[ERROR] select result version: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50004, "update record failed")
+		return
+	}
+	s.success(c, gin.H{"record": toHomeworkResp(updated)})
+}
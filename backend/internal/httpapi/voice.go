@@ -0,0 +1,147 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsdot-aibuddy/backend/internal/media"
+	"whatsdot-aibuddy/backend/internal/openai"
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+var errTranscriberConfigMissing = errors.New("transcription not configured: set STT_API_KEY or OPENAI_API_KEY")
+
+// mockTranscript stands in for the transcription provider when ANALYZE_MOCK is on.
+const mockTranscript = "24 乘 15 等于多少？"
+
+// handleAnalyzeVoice analyzes a question asked out loud: the recording is
+// transcribed, the transcript is analyzed as text and stored as the question
+// text, and the recording is kept as the record's source.
+func (s *Server) handleAnalyzeVoice(c *gin.Context) {
+	deviceID := deviceIDFromRequest(c)
+	if deviceID == "" {
+		s.fail(c, http.StatusBadRequest, 40001, "device_id required")
+		return
+	}
+
+	mode := normalizeMode(c.PostForm("mode"))
+	child, ok := s.childFromRequest(c, deviceID)
+	if !ok {
+		return
+	}
+	fileHeader, err := c.FormFile("audio")
+	if err != nil {
+		s.fail(c, http.StatusBadRequest, 40030, "audio file required")
+		return
+	}
+	audio, audioURL, err := s.readAndSaveAudio(fileHeader)
+	if err != nil {
+		s.failAudioUpload(c, err)
+		return
+	}
+
+	transcript, err := s.transcribe(c, audio)
+	if err != nil {
+		log.Printf("[ERROR] transcribe: %v", err)
+		if errors.Is(err, errTranscriberConfigMissing) {
+			s.fail(c, http.StatusInternalServerError, 50007, err.Error())
+			return
+		}
+		// Silence is the user's retake, answered like an unreadable photo.
+		if errors.Is(err, openai.ErrEmptyTranscription) {
+			s.failAnalyze(c, "transcribe", &unreadableError{result: openai.AnalyzeResult{
+				Legibility: openai.LegibilityUnreadable,
+				RetakeTips: openai.RerecordTips(s.language(c)),
+			}, message: "recording inaudible, please record again"})
+			return
+		}
+		s.fail(c, http.StatusBadGateway, 50031, "transcribe audio failed")
+		return
+	}
+
+	result, err := s.analyze(c, s.withLearner(c, child, openai.AnalyzeInput{Mode: mode, Transcript: transcript}))
+	if err != nil {
+		s.failAnalyze(c, "analyze voice", err)
+		return
+	}
+
+	rec, err := s.Store.CreateHomework(c.Request.Context(), store.NewHomework{
+		DeviceID:     deviceID,
+		Mode:         mode,
+		Kind:         store.KindVoice,
+		ChildID:      child.ID,
+		Subject:      result.Subject,
+		ImageURL:     audioURL,
+		QuestionText: transcript,
+		Grade:        result.SuggestedGrade,
+		Result:       result,
+		Meta:         s.resultMeta(),
+	})
+	if err != nil {
+		log.Printf("[ERROR] create homework: %v", err)
+		s.fail(c, http.StatusInternalServerError, 50002, "save record failed")
+		return
+	}
+
+	s.success(c, gin.H{"record": toHomeworkResp(rec)})
+}
+
+// readAndSaveAudio validates a recording by content and stores it like an image upload.
+func (s *Server) readAndSaveAudio(file *multipart.FileHeader) (media.Audio, string, error) {
+	max := s.maxAudioBytes()
+	if file.Size > max {
+		return media.Audio{}, "", media.ErrTooLarge
+	}
+	src, err := file.Open()
+	if err != nil {
+		return media.Audio{}, "", err
+	}
+	defer src.Close()
+	b, err := media.ReadLimited(src, max)
+	if err != nil {
+		return media.Audio{}, "", err
+	}
+	audio, err := media.ValidateAudio(b, max)
+	if err != nil {
+		return media.Audio{}, "", err
+	}
+	url, err := s.saveUpload(audio.Bytes, audio.Ext)
+	if err != nil {
+		return media.Audio{}, "", err
+	}
+	return audio, url, nil
+}
+
+func (s *Server) transcribe(c *gin.Context, audio media.Audio) (string, error) {
+	if s.AnalyzeMock {
+		return mockTranscript, nil
+	}
+	if s.Transcriber == nil || strings.TrimSpace(s.Transcriber.APIKey) == "" {
+		return "", errTranscriberConfigMissing
+	}
+	return s.Transcriber.Transcribe(c.Request.Context(), audio.Bytes, "question"+audio.Ext, audio.ContentType)
+}
+
+func (s *Server) failAudioUpload(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		s.fail(c, http.StatusRequestEntityTooLarge, 41301, fmt.Sprintf("audio too large, max %dMB", s.maxAudioBytes()>>20))
+	case errors.Is(err, media.ErrUnsupportedAudio):
+		s.fail(c, http.StatusUnsupportedMediaType, 41502, err.Error())
+	default:
+		s.fail(c, http.StatusBadRequest, 40003, err.Error())
+	}
+}
+
+func (s *Server) maxAudioBytes() int64 {
+	if s.AudioMaxBytes > 0 {
+		return s.AudioMaxBytes
+	}
+	return media.DefaultMaxAudioBytes
+}
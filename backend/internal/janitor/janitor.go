@@ -0,0 +1,176 @@
+package janitor
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Store is the subset of store.Store the janitor needs.
+type Store interface {
+	ReferencedUploads(ctx context.Context) (map[string]bool, error)
+	ExpireRecordImages(ctx context.Context, before time.Time) (int64, error)
+	DeleteExpiredUploadSessions(ctx context.Context, before time.Time) ([]string, error)
+}
+
+// Janitor removes upload files no record points at and, when Retention is set,
+// detaches photos from records older than the retention window.
+type Janitor struct {
+	Store     Store
+	UploadDir string
+	// Grace protects files whose analysis is still in flight; only orphans older
+	// than this are deleted.
+	Grace time.Duration
+	// Retention is how long record photos are kept. Zero keeps them forever.
+	Retention time.Duration
+	// PartialDir holds in-progress chunked uploads named by session id; files of
+	// expired sessions are removed with them.
+	PartialDir string
+	// CacheDir holds regenerable files such as speech audio; those not used for
+	// CacheMaxAge are removed. Zero CacheMaxAge keeps them forever.
+	CacheDir    string
+	CacheMaxAge time.Duration
+	Now         func() time.Time
+}
+
+type Report struct {
+	ExpiredRecords  int64
+	ExpiredSessions int
+	Scanned         int
+	Deleted         int
+	FreedBytes      int64
+	CachePruned     int
+}
+
+func (j *Janitor) now() time.Time {
+	if j.Now != nil {
+		return j.Now()
+	}
+	return time.Now()
+}
+
+// RunOnce performs one retention pass followed by one orphan sweep.
+func (j *Janitor) RunOnce(ctx context.Context) (Report, error) {
+	var rep Report
+	now := j.now()
+
+	if j.Retention > 0 {
+		n, err := j.Store.ExpireRecordImages(ctx, now.Add(-j.Retention))
+		if err != nil {
+			return rep, err
+		}
+		rep.ExpiredRecords = n
+	}
+
+	ids, err := j.Store.DeleteExpiredUploadSessions(ctx, now)
+	if err != nil {
+		return rep, err
+	}
+	rep.ExpiredSessions = len(ids)
+	if j.PartialDir != "" {
+		for _, id := range ids {
+			if err := os.Remove(filepath.Join(j.PartialDir, filepath.Base(id))); err != nil && !os.IsNotExist(err) {
+				log.Printf("[WARN] janitor remove partial %s: %v", id, err)
+			}
+		}
+	}
+
+	if j.CacheDir != "" && j.CacheMaxAge > 0 {
+		n, freed, err := pruneOlder(j.CacheDir, now.Add(-j.CacheMaxAge))
+		if err != nil {
+			return rep, err
+		}
+		rep.CachePruned = n
+		rep.FreedBytes += freed
+	}
+
+	refs, err := j.Store.ReferencedUploads(ctx)
+	if err != nil {
+		return rep, err
+	}
+	entries, err := os.ReadDir(j.UploadDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return rep, nil
+		}
+		return rep, err
+	}
+	for _, e := range entries {
+		// Sub-directories hold caches and in-progress uploads with their own lifecycle.
+		if !e.Type().IsRegular() {
+			continue
+		}
+		rep.Scanned++
+		if refs[e.Name()] {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if now.Sub(info.ModTime()) < j.Grace {
+			continue
+		}
+		if err := os.Remove(filepath.Join(j.UploadDir, e.Name())); err != nil {
+			log.Printf("[WARN] janitor remove %s: %v", e.Name(), err)
+			continue
+		}
+		rep.Deleted++
+		rep.FreedBytes += info.Size()
+	}
+	return rep, nil
+}
+
+// pruneOlder removes the regular files in dir last modified before cutoff.
+func pruneOlder(dir string, cutoff time.Time) (int, int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	n, freed := 0, int64(0)
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			log.Printf("[WARN] janitor remove cache %s: %v", e.Name(), err)
+			continue
+		}
+		n++
+		freed += info.Size()
+	}
+	return n, freed, nil
+}
+
+// Run calls RunOnce every interval until ctx is done.
+func (j *Janitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		j.logRun(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Janitor) logRun(ctx context.Context) {
+	rep, err := j.RunOnce(ctx)
+	if err != nil {
+		log.Printf("[ERROR] janitor: %v", err)
+		return
+	}
+	log.Printf("[JANITOR] expired_records=%d expired_sessions=%d scanned=%d deleted=%d cache_pruned=%d freed_bytes=%d",
+		rep.ExpiredRecords, rep.ExpiredSessions, rep.Scanned, rep.Deleted, rep.CachePruned, rep.FreedBytes)
+}
@@ -0,0 +1,114 @@
+package janitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	refs          map[string]bool
+	expiredBefore time.Time
+}
+
+func (f *fakeStore) ReferencedUploads(context.Context) (map[string]bool, error) {
+	return f.refs, nil
+}
+
+func (f *fakeStore) ExpireRecordImages(_ context.Context, before time.Time) (int64, error) {
+	f.expiredBefore = before
+	delete(f.refs, "old.jpg")
+	return 1, nil
+}
+
+func (f *fakeStore) DeleteExpiredUploadSessions(context.Context, time.Time) ([]string, error) {
+	return []string{"abc"}, nil
+}
+
+func writeFile(t *testing.T, dir, name string, modTime time.Time) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunOnceDeletesOnlyStaleOrphans(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	writeFile(t, dir, "kept.jpg", now.Add(-72*time.Hour))
+	writeFile(t, dir, "orphan.jpg", now.Add(-72*time.Hour))
+	writeFile(t, dir, "fresh.jpg", now.Add(-time.Minute))
+	writeFile(t, dir, "old.jpg", now.Add(-40*24*time.Hour))
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	partialDir := filepath.Join(dir, ".partial")
+	if err := os.Mkdir(partialDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, partialDir, "abc", now)
+
+	fs := &fakeStore{refs: map[string]bool{"kept.jpg": true, "old.jpg": true}}
+	j := &Janitor{
+		Store:      fs,
+		UploadDir:  dir,
+		Grace:      24 * time.Hour,
+		Retention:  30 * 24 * time.Hour,
+		PartialDir: partialDir,
+		Now:        func() time.Time { return now },
+	}
+	rep, err := j.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fs.expiredBefore.Equal(now.Add(-30 * 24 * time.Hour)) {
+		t.Fatalf("unexpected retention cutoff %v", fs.expiredBefore)
+	}
+	if rep.ExpiredRecords != 1 || rep.ExpiredSessions != 1 || rep.Scanned != 4 || rep.Deleted != 2 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	for name, want := range map[string]bool{"kept.jpg": true, "fresh.jpg": true, "orphan.jpg": false, "old.jpg": false, "nested": true, ".partial/abc": false} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != want {
+			t.Fatalf("%s exists=%v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRunOncePrunesStaleCache(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "tts")
+	if err := os.Mkdir(cacheDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	writeFile(t, cacheDir, "stale.mp3", now.Add(-31*24*time.Hour))
+	writeFile(t, cacheDir, "used.mp3", now.Add(-2*24*time.Hour))
+
+	j := &Janitor{
+		Store:       &fakeStore{refs: map[string]bool{}},
+		UploadDir:   dir,
+		CacheDir:    cacheDir,
+		CacheMaxAge: 30 * 24 * time.Hour,
+		Now:         func() time.Time { return now },
+	}
+	rep, err := j.RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.CachePruned != 1 || rep.Deleted != 0 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	for name, want := range map[string]bool{"stale.mp3": false, "used.mp3": true} {
+		_, err := os.Stat(filepath.Join(cacheDir, name))
+		if got := err == nil; got != want {
+			t.Fatalf("%s exists=%v, want %v", name, got, want)
+		}
+	}
+}
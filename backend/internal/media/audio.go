@@ -0,0 +1,55 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+)
+
+var ErrUnsupportedAudio = errors.New("only mp3, m4a, wav, ogg, webm and flac audio is supported")
+
+// DefaultMaxAudioBytes is below the 25MB limit of OpenAI-compatible transcription.
+const DefaultMaxAudioBytes int64 = 10 * 1024 * 1024
+
+// Audio is a recording that passed validation.
+type Audio struct {
+	Bytes       []byte
+	ContentType string
+	Ext         string
+}
+
+// ValidateAudio identifies a recording by its leading bytes; the declared file
+// name or content type is not trusted.
+func ValidateAudio(b []byte, maxBytes int64) (Audio, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxAudioBytes
+	}
+	if len(b) == 0 {
+		return Audio{}, ErrEmpty
+	}
+	if int64(len(b)) > maxBytes {
+		return Audio{}, ErrTooLarge
+	}
+	ext, contentType := sniffAudio(b)
+	if ext == "" {
+		return Audio{}, ErrUnsupportedAudio
+	}
+	return Audio{Bytes: b, ContentType: contentType, Ext: ext}, nil
+}
+
+func sniffAudio(b []byte) (ext, contentType string) {
+	switch {
+	case bytes.HasPrefix(b, []byte("ID3")), len(b) > 1 && b[0] == 0xFF && b[1]&0xE0 == 0xE0:
+		return ".mp3", "audio/mpeg"
+	case len(b) >= 12 && bytes.Equal(b[:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WAVE")):
+		return ".wav", "audio/wav"
+	case len(b) >= 12 && bytes.Equal(b[4:8], []byte("ftyp")):
+		return ".m4a", "audio/mp4"
+	case bytes.HasPrefix(b, []byte("OggS")):
+		return ".ogg", "audio/ogg"
+	case bytes.HasPrefix(b, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return ".webm", "audio/webm"
+	case bytes.HasPrefix(b, []byte("fLaC")):
+		return ".flac", "audio/flac"
+	}
+	return "", ""
+}
@@ -0,0 +1,37 @@
+package media
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAudio(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		ext  string
+	}{
+		{"mp3 id3", []byte("ID3\x04\x00rest"), ".mp3"},
+		{"mp3 frame", []byte{0xFF, 0xFB, 0x90, 0x00}, ".mp3"},
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), ".wav"},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A \x00"), ".m4a"},
+		{"ogg", []byte("OggS\x00\x02"), ".ogg"},
+		{"webm", []byte{0x1A, 0x45, 0xDF, 0xA3, 0x01}, ".webm"},
+		{"flac", []byte("fLaC\x00"), ".flac"},
+	}
+	for _, tc := range cases {
+		a, err := ValidateAudio(tc.data, 0)
+		if err != nil || a.Ext != tc.ext {
+			t.Fatalf("%s: got %q, %v", tc.name, a.Ext, err)
+		}
+	}
+	if _, err := ValidateAudio(testPNG(t, 2, 2), 0); !errors.Is(err, ErrUnsupportedAudio) {
+		t.Fatalf("png should be rejected, got %v", err)
+	}
+	if _, err := ValidateAudio(nil, 0); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("empty should be rejected, got %v", err)
+	}
+	if _, err := ValidateAudio([]byte("ID3\x04\x00rest"), 4); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("oversized should be rejected, got %v", err)
+	}
+}
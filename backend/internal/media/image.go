@@ -0,0 +1,449 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/webp"
+)
+
+var (
+	ErrEmpty          = errors.New("empty file")
+	ErrTooLarge       = errors.New("file too large")
+	ErrHEIC           = errors.New("HEIC/HEIF image is not supported, please convert to JPG or PNG")
+	ErrUnsupported    = errors.New("only jpg, png and webp images are supported")
+	ErrUndecodable    = errors.New("image cannot be decoded")
+	ErrTooManyPixels  = errors.New("image dimensions too large")
+	ErrTrailingData   = errors.New("image contains unexpected trailing data")
+	ErrMalformedImage = errors.New("image structure is malformed")
+)
+
+// Limits bounds what an upload may contain. Zero values fall back to the defaults.
+type Limits struct {
+	MaxBytes  int64
+	MaxSide   int
+	MaxPixels int64
+}
+
+const (
+	DefaultMaxBytes  int64 = 8 * 1024 * 1024
+	DefaultMaxSide         = 10000
+	DefaultMaxPixels int64 = 25_000_000
+)
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = DefaultMaxBytes
+	}
+	if l.MaxSide <= 0 {
+		l.MaxSide = DefaultMaxSide
+	}
+	if l.MaxPixels <= 0 {
+		l.MaxPixels = DefaultMaxPixels
+	}
+	return l
+}
+
+// Image is an upload that passed validation. Bytes is the sanitized content that
+// should be stored and sent to the model.
+type Image struct {
+	Bytes       []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// ReadLimited reads r fully and fails with ErrTooLarge instead of truncating when
+// it holds more than max bytes.
+func ReadLimited(r io.Reader, max int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > max {
+		return nil, ErrTooLarge
+	}
+	return b, nil
+}
+
+// ValidateImage checks that b is a single, fully decodable jpg/png/webp within
+// limits, and returns a copy with location metadata removed.
+func ValidateImage(b []byte, limits Limits) (Image, error) {
+	limits = limits.withDefaults()
+	if len(b) == 0 {
+		return Image{}, ErrEmpty
+	}
+	if int64(len(b)) > limits.MaxBytes {
+		return Image{}, ErrTooLarge
+	}
+	if isHEIF(b) {
+		return Image{}, ErrHEIC
+	}
+
+	switch http.DetectContentType(b) {
+	case "image/jpeg":
+		return validateJPEG(b, limits)
+	case "image/png":
+		return validatePNG(b, limits)
+	case "image/webp":
+		return validateWebP(b, limits)
+	default:
+		return Image{}, ErrUnsupported
+	}
+}
+
+func checkDimensions(w, h int, limits Limits) error {
+	if w <= 0 || h <= 0 {
+		return ErrUndecodable
+	}
+	if w > limits.MaxSide || h > limits.MaxSide || int64(w)*int64(h) > limits.MaxPixels {
+		return fmt.Errorf("%w: %dx%d", ErrTooManyPixels, w, h)
+	}
+	return nil
+}
+
+// decodeChecked reads the header first so oversized images are rejected before
+// any pixel buffer is allocated, then decodes the whole image.
+func decodeChecked(b []byte, limits Limits, decodeConfig func(io.Reader) (image.Config, error), decode func(io.Reader) (image.Image, error)) (int, int, error) {
+	cfg, err := decodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return 0, 0, ErrUndecodable
+	}
+	if err := checkDimensions(cfg.Width, cfg.Height, limits); err != nil {
+		return 0, 0, err
+	}
+	if _, err := decode(bytes.NewReader(b)); err != nil {
+		return 0, 0, ErrUndecodable
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+func validateJPEG(b []byte, limits Limits) (Image, error) {
+	clean, err := sanitizeJPEG(b)
+	if err != nil {
+		return Image{}, err
+	}
+	w, h, err := decodeChecked(clean, limits, jpeg.DecodeConfig, jpeg.Decode)
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{Bytes: clean, ContentType: "image/jpeg", Ext: ".jpg", Width: w, Height: h}, nil
+}
+
+func validatePNG(b []byte, limits Limits) (Image, error) {
+	clean, err := sanitizePNG(b)
+	if err != nil {
+		return Image{}, err
+	}
+	w, h, err := decodeChecked(clean, limits, png.DecodeConfig, png.Decode)
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{Bytes: clean, ContentType: "image/png", Ext: ".png", Width: w, Height: h}, nil
+}
+
+// validateWebP checks the RIFF container and frame header, then decodes the whole
+// image. Animated WebP cannot be decoded and is rejected.
+func validateWebP(b []byte, limits Limits) (Image, error) {
+	clean, w, h, err := sanitizeWebP(b)
+	if err != nil {
+		return Image{}, err
+	}
+	if err := checkDimensions(w, h, limits); err != nil {
+		return Image{}, err
+	}
+	if w, h, err = decodeChecked(clean, limits, webp.DecodeConfig, webp.Decode); err != nil {
+		return Image{}, err
+	}
+	return Image{Bytes: clean, ContentType: "image/webp", Ext: ".webp", Width: w, Height: h}, nil
+}
+
+// isHEIF detects ISO-BMFF images (HEIC/HEIF/AVIF), which phones produce by default
+// but http.DetectContentType does not recognize.
+func isHEIF(b []byte) bool {
+	if len(b) < 12 || string(b[4:8]) != "ftyp" {
+		return false
+	}
+	switch string(b[8:12]) {
+	case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1", "avif", "avis":
+		return true
+	}
+	return false
+}
+
+// sanitizeJPEG walks the marker segments, drops GPS data from the Exif block and cuts
+// everything after EOI. Any trailing data other than padding is treated as a
+// polyglot and rejected.
+func sanitizeJPEG(b []byte) ([]byte, error) {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return nil, ErrMalformedImage
+	}
+	out := make([]byte, 0, len(b))
+	out = append(out, 0xFF, 0xD8)
+	i := 2
+	for {
+		if i >= len(b) || b[i] != 0xFF {
+			return nil, ErrMalformedImage
+		}
+		for i < len(b) && b[i] == 0xFF {
+			i++
+		}
+		if i >= len(b) {
+			return nil, ErrMalformedImage
+		}
+		marker := b[i]
+		i++
+		if marker == 0xD9 {
+			out = append(out, 0xFF, 0xD9)
+			break
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out = append(out, 0xFF, marker)
+			continue
+		}
+		if i+2 > len(b) {
+			return nil, ErrMalformedImage
+		}
+		n := int(binary.BigEndian.Uint16(b[i:]))
+		if n < 2 || i+n > len(b) {
+			return nil, ErrMalformedImage
+		}
+		seg := b[i : i+n]
+		i += n
+
+		if marker == 0xE1 && bytes.HasPrefix(seg[2:], []byte("Exif\x00\x00")) {
+			exif := append([]byte(nil), seg[8:]...)
+			if err := stripGPS(exif); err != nil {
+				// Unparseable Exif is dropped whole rather than stored as-is.
+				continue
+			}
+			out = append(out, 0xFF, marker)
+			out = append(out, seg[:8]...)
+			out = append(out, exif...)
+			continue
+		}
+		out = append(out, 0xFF, marker)
+		out = append(out, seg...)
+
+		if marker == 0xDA {
+			start := i
+			for i < len(b) {
+				if b[i] != 0xFF || i+1 >= len(b) {
+					i++
+					continue
+				}
+				next := b[i+1]
+				if next == 0x00 || (next >= 0xD0 && next <= 0xD7) {
+					i += 2
+					continue
+				}
+				if next == 0xFF {
+					i++
+					continue
+				}
+				break
+			}
+			out = append(out, b[start:i]...)
+		}
+	}
+
+	if !isPadding(b[i:]) {
+		return nil, ErrTrailingData
+	}
+	return out, nil
+}
+
+// stripGPS empties the GPS IFD of a TIFF-structured Exif block in place.
+func stripGPS(tiff []byte) error {
+	if len(tiff) < 8 {
+		return ErrMalformedImage
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return ErrMalformedImage
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return ErrMalformedImage
+	}
+	ifd0 := int(order.Uint32(tiff[4:]))
+	if ifd0+2 > len(tiff) {
+		return ErrMalformedImage
+	}
+	count := int(order.Uint16(tiff[ifd0:]))
+	if ifd0+2+count*12 > len(tiff) {
+		return ErrMalformedImage
+	}
+	for k := 0; k < count; k++ {
+		entry := tiff[ifd0+2+k*12:]
+		if order.Uint16(entry) != 0x8825 {
+			continue
+		}
+		gps := int(order.Uint32(entry[8:]))
+		if gps+2 > len(tiff) {
+			return ErrMalformedImage
+		}
+		n := int(order.Uint16(tiff[gps:]))
+		end := gps + 2 + n*12
+		if end > len(tiff) {
+			return ErrMalformedImage
+		}
+		for e := 0; e < n; e++ {
+			field := tiff[gps+2+e*12:]
+			size := tiffTypeSize(order.Uint16(field[2:])) * int(order.Uint32(field[4:]))
+			if size > 4 {
+				off := int(order.Uint32(field[8:]))
+				if off >= 0 && size <= len(tiff) && off <= len(tiff)-size {
+					clear(tiff[off : off+size])
+				}
+			}
+		}
+		clear(tiff[gps+2 : end])
+		order.PutUint16(tiff[gps:], 0)
+	}
+	return nil
+}
+
+func tiffTypeSize(t uint16) int {
+	switch t {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11:
+		return 4
+	case 5, 10, 12:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// sanitizePNG walks the chunks up to IEND, drops eXIf chunks and rejects anything
+// appended after IEND.
+func sanitizePNG(b []byte) ([]byte, error) {
+	const sig = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(b, []byte(sig)) {
+		return nil, ErrMalformedImage
+	}
+	out := make([]byte, 0, len(b))
+	out = append(out, sig...)
+	i := len(sig)
+	for {
+		if i+12 > len(b) {
+			return nil, ErrMalformedImage
+		}
+		n := int(binary.BigEndian.Uint32(b[i:]))
+		if n < 0 || n > len(b)-i-12 {
+			return nil, ErrMalformedImage
+		}
+		typ := string(b[i+4 : i+8])
+		chunk := b[i : i+12+n]
+		i += 12 + n
+		if typ != "eXIf" {
+			out = append(out, chunk...)
+		}
+		if typ == "IEND" {
+			break
+		}
+	}
+	if !isPadding(b[i:]) {
+		return nil, ErrTrailingData
+	}
+	return out, nil
+}
+
+// sanitizeWebP checks that the RIFF size matches the file, drops the EXIF chunk and
+// reads the canvas size from the first frame header.
+func sanitizeWebP(b []byte) ([]byte, int, int, error) {
+	if len(b) < 20 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return nil, 0, 0, ErrMalformedImage
+	}
+	size := int(binary.LittleEndian.Uint32(b[4:]))
+	if size < 12 || size+8 > len(b) {
+		return nil, 0, 0, ErrMalformedImage
+	}
+	if !isPadding(b[size+8:]) {
+		return nil, 0, 0, ErrTrailingData
+	}
+	body := b[12 : size+8]
+
+	out := make([]byte, 12, len(b))
+	copy(out, b[:12])
+	w, h := 0, 0
+	vp8x := -1
+	for i := 0; i < len(body); {
+		if i+8 > len(body) {
+			return nil, 0, 0, ErrMalformedImage
+		}
+		fourcc := string(body[i : i+4])
+		n := int(binary.LittleEndian.Uint32(body[i+4:]))
+		padded := n + n&1
+		if n < 0 || padded > len(body)-i-8 {
+			return nil, 0, 0, ErrMalformedImage
+		}
+		data := body[i+8 : i+8+n]
+		chunk := body[i : i+8+padded]
+		i += 8 + padded
+
+		switch fourcc {
+		case "EXIF":
+			continue
+		case "VP8X":
+			if n < 10 {
+				return nil, 0, 0, ErrMalformedImage
+			}
+			vp8x = len(out) + 8
+			w = 1 + (int(data[4]) | int(data[5])<<8 | int(data[6])<<16)
+			h = 1 + (int(data[7]) | int(data[8])<<8 | int(data[9])<<16)
+		case "VP8 ":
+			if n < 10 || data[3] != 0x9D || data[4] != 0x01 || data[5] != 0x2A {
+				return nil, 0, 0, ErrMalformedImage
+			}
+			if w == 0 {
+				w = int(binary.LittleEndian.Uint16(data[6:]) & 0x3FFF)
+				h = int(binary.LittleEndian.Uint16(data[8:]) & 0x3FFF)
+			}
+		case "VP8L":
+			if n < 5 || data[0] != 0x2F {
+				return nil, 0, 0, ErrMalformedImage
+			}
+			if w == 0 {
+				bits := binary.LittleEndian.Uint32(data[1:])
+				w = int(bits&0x3FFF) + 1
+				h = int((bits>>14)&0x3FFF) + 1
+			}
+		}
+		out = append(out, chunk...)
+	}
+	if w == 0 || h == 0 {
+		return nil, 0, 0, ErrMalformedImage
+	}
+	if vp8x >= 0 {
+		// Clear the "has EXIF" flag now that the chunk is gone.
+		out[vp8x] &^= 0x08
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, w, h, nil
+}
+
+func isPadding(b []byte) bool {
+	for _, c := range b {
+		if c != 0x00 && c != 0xFF {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withGPSExif inserts an APP1 Exif segment whose IFD0 points at a GPS IFD holding a
+// latitude rational, right after SOI.
+func withGPSExif(jpg []byte) []byte {
+	le := binary.LittleEndian
+	tiff := make([]byte, 64)
+	copy(tiff, "II")
+	le.PutUint16(tiff[2:], 42)
+	le.PutUint32(tiff[4:], 8)
+	// IFD0 with a single GPS pointer entry.
+	le.PutUint16(tiff[8:], 1)
+	le.PutUint16(tiff[10:], 0x8825)
+	le.PutUint16(tiff[12:], 4)
+	le.PutUint32(tiff[14:], 1)
+	le.PutUint32(tiff[18:], 26)
+	// GPS IFD at 26 with a GPSLatitude rational stored out of line at 44.
+	le.PutUint16(tiff[26:], 1)
+	le.PutUint16(tiff[28:], 0x0002)
+	le.PutUint16(tiff[30:], 5)
+	le.PutUint32(tiff[32:], 1)
+	le.PutUint32(tiff[36:], 44)
+	le.PutUint32(tiff[44:], 39)
+	le.PutUint32(tiff[48:], 1)
+
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(out[4:], uint16(len(seg)+2))
+	out = append(out, seg...)
+	return append(out, jpg[2:]...)
+}
+
+func TestValidateImageAcceptsJPEGAndPNG(t *testing.T) {
+	img, err := ValidateImage(testJPEG(t, 40, 30), Limits{})
+	if err != nil {
+		t.Fatalf("jpeg rejected: %v", err)
+	}
+	if img.Ext != ".jpg" || img.Width != 40 || img.Height != 30 {
+		t.Fatalf("unexpected jpeg result: %+v", img)
+	}
+	img, err = ValidateImage(testPNG(t, 20, 10), Limits{})
+	if err != nil {
+		t.Fatalf("png rejected: %v", err)
+	}
+	if img.Ext != ".png" || img.ContentType != "image/png" {
+		t.Fatalf("unexpected png result: %+v", img)
+	}
+}
+
+func TestValidateImageStripsGPS(t *testing.T) {
+	src := withGPSExif(testJPEG(t, 16, 16))
+	img, err := ValidateImage(src, Limits{})
+	if err != nil {
+		t.Fatalf("jpeg with exif rejected: %v", err)
+	}
+	if !bytes.Contains(img.Bytes, []byte("Exif\x00\x00")) {
+		t.Fatalf("expected exif block to be kept")
+	}
+	lat := make([]byte, 8)
+	binary.LittleEndian.PutUint32(lat, 39)
+	binary.LittleEndian.PutUint32(lat[4:], 1)
+	if bytes.Contains(img.Bytes, lat) {
+		t.Fatalf("expected gps latitude to be removed")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(img.Bytes)); err != nil {
+		t.Fatalf("sanitized jpeg no longer decodes: %v", err)
+	}
+}
+
+func testWebP(t *testing.T) []byte {
+	t.Helper()
+	b, err := os.ReadFile("testdata/gopher.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestValidateImageAcceptsWebP(t *testing.T) {
+	img, err := ValidateImage(testWebP(t), Limits{})
+	if err != nil {
+		t.Fatalf("webp rejected: %v", err)
+	}
+	if img.ContentType != "image/webp" || img.Width <= 0 || img.Height <= 0 {
+		t.Fatalf("unexpected image: %s %dx%d", img.ContentType, img.Width, img.Height)
+	}
+}
+
+func TestValidateImageRejects(t *testing.T) {
+	jpg := testJPEG(t, 16, 16)
+	// A WebP whose container and header are valid but whose pixel data is not.
+	badWebP := testWebP(t)
+	for i := 30; i < len(badWebP); i++ {
+		badWebP[i] = 0xFF
+	}
+	heic := append([]byte{0, 0, 0, 0x18}, []byte("ftypheic\x00\x00\x00\x00mif1heic")...)
+
+	cases := []struct {
+		name   string
+		data   []byte
+		limits Limits
+		want   error
+	}{
+		{"empty", nil, Limits{}, ErrEmpty},
+		{"too large", jpg, Limits{MaxBytes: 10}, ErrTooLarge},
+		{"heic", heic, Limits{}, ErrHEIC},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), Limits{}, ErrUnsupported},
+		{"truncated", jpg[:len(jpg)/2], Limits{}, ErrMalformedImage},
+		{"undecodable webp", badWebP, Limits{}, ErrUndecodable},
+		{"mp4 tail", append(append([]byte{}, jpg...), []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00")...), Limits{}, ErrTrailingData},
+		{"binary tail", append(append([]byte{}, jpg...), 0x01, 0x02, 0x03, 0x04), Limits{}, ErrTrailingData},
+		{"zip polyglot", append(append([]byte{}, jpg...), []byte("PK\x03\x04payload")...), Limits{}, ErrTrailingData},
+		{"png html polyglot", append(testPNG(t, 4, 4), []byte("<html><script>")...), Limits{}, ErrTrailingData},
+		{"too many pixels", testPNG(t, 300, 300), Limits{MaxPixels: 1000}, ErrTooManyPixels},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ValidateImage(tc.data, tc.limits)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestReadLimitedRejectsInsteadOfTruncating(t *testing.T) {
+	if _, err := ReadLimited(bytes.NewReader(make([]byte, 11)), 10); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+	b, err := ReadLimited(bytes.NewReader(make([]byte, 10)), 10)
+	if err != nil || len(b) != 10 {
+		t.Fatalf("expected 10 bytes, got %d, %v", len(b), err)
+	}
+}
@@ -0,0 +1,105 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	oosdk "github.com/openai/openai-go"
+	"github.com/openai/openai-go/shared"
+)
+
+// ChatTurn is one prior message of a follow-up thread. Role is "user" or "assistant".
+type ChatTurn struct {
+	Role    string
+	Content string
+}
+
+// FollowUpInput is the context for continuing a conversation about an analyzed record.
+type FollowUpInput struct {
+	Image        []byte
+	ContentType  string
+	Mode         string
+	QuestionText string
+	Result       AnalyzeResult
+	History      []ChatTurn
+	Message      string
+	// Language is the output language of the reply; see NormalizeLanguage.
+	Language string
+}
+
+// maxFollowUpHistory caps how many prior turns are replayed to the model.
+const maxFollowUpHistory = 20
+
+// FollowUp answers a parent's follow-up question with the original image, question and
+// analysis as context. The reply is plain text and follows the record's mode rule;
+// a noanswer reply that still gives the answer away is redacted.
+func (c *Client) FollowUp(ctx context.Context, in FollowUpInput) (string, error) {
+	resultJSON, err := json.Marshal(in.Result)
+	if err != nil {
+		return "", err
+	}
+	contextText := fmt.Sprintf("题目：%s\n之前给家长的分析（JSON）：%s", in.QuestionText, resultJSON)
+
+	parts := []oosdk.ChatCompletionContentPartUnionParam{oosdk.TextContentPart(contextText)}
+	if len(in.Image) > 0 {
+		part, _ := imagePart(in.Image, in.ContentType)
+		parts = append(parts, part)
+	}
+
+	messages := []oosdk.ChatCompletionMessageParamUnion{
+		oosdk.SystemMessage(followUpSystemPrompt(in.Mode, in.Language)),
+		oosdk.UserMessage(parts),
+	}
+	history := in.History
+	if len(history) > maxFollowUpHistory {
+		history = history[len(history)-maxFollowUpHistory:]
+	}
+	for _, t := range history {
+		if t.Role == "assistant" {
+			messages = append(messages, oosdk.AssistantMessage(t.Content))
+		} else {
+			messages = append(messages, oosdk.UserMessage(t.Content))
+		}
+	}
+	messages = append(messages, oosdk.UserMessage(in.Message))
+
+	reply, err := c.completeText(ctx, "followup:"+in.Mode, messages, 0.4)
+	if err != nil || in.Mode != "noanswer" {
+		return reply, err
+	}
+	return c.redactReplyLeak(ctx, in, reply), nil
+}
+
+// completeText runs a free-form chat completion and returns the trimmed reply.
+func (c *Client) completeText(ctx context.Context, tag string, messages []oosdk.ChatCompletionMessageParamUnion, temperature float64) (string, error) {
+	if strings.TrimSpace(c.APIKey) == "" {
+		return "", errors.New("OPENAI_API_KEY is empty")
+	}
+	log.Printf("[OPENAI_REQ] endpoint=%s domain=%s model=%s mode=%s messages=%d",
+		c.BaseURL, extractDomain(c.BaseURL), c.Model, tag, len(messages))
+
+	resp, err := c.SDK.Chat.Completions.New(ctx, oosdk.ChatCompletionNewParams{
+		Model:       shared.ChatModel(c.Model),
+		Messages:    messages,
+		Temperature: oosdk.Float(temperature),
+	})
+	if err != nil {
+		log.Printf("[OPENAI_ERR] endpoint=%s domain=%s model=%s mode=%s err=%v",
+			c.BaseURL, extractDomain(c.BaseURL), c.Model, tag, err)
+		return "", fmt.Errorf("chat completion failed: %w", err)
+	}
+	log.Printf("[OPENAI_RESP] request_id=%s model=%s prompt_tokens=%d completion_tokens=%d total_tokens=%d",
+		resp.ID, resp.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, resp.Usage.TotalTokens)
+	if len(resp.Choices) == 0 {
+		return "", errors.New("empty choices")
+	}
+	content := strings.TrimSpace(resp.Choices[0].Message.Content)
+	if content == "" {
+		return "", errors.New("empty completion content")
+	}
+	return content, nil
+}
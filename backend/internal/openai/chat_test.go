@@ -0,0 +1,72 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chatServer answers chat completions with reply, or with answer when the request
+// asks for the separate final answer.
+func chatServer(reply, answer string, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		*calls++
+		var req struct {
+			ResponseFormat json.RawMessage `json:"response_format"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		content := reply
+		if len(req.ResponseFormat) > 0 {
+			b, _ := json.Marshal(map[string]string{"final_answer": answer})
+			content = string(b)
+		}
+		b, _ := json.Marshal(content)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"x","object":"chat.completion","created":0,"model":"m",`+
+			`"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}],`+
+			`"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`, b)
+	}))
+}
+
+func TestFollowUpRedactsLeakedAnswer(t *testing.T) {
+	leaking := "孩子算得对，24×15 的结果就是360，可以再让他说说理由。"
+	cases := []struct {
+		name   string
+		mode   string
+		result AnalyzeResult
+		answer string
+		leaked bool
+		calls  int
+	}{
+		{"expression", "noanswer", AnalyzeResult{Math: &MathDetails{Expression: "24*15"}}, "", true, 1},
+		{"separate answer", "noanswer", AnalyzeResult{}, "360", true, 2},
+		{"other answer", "noanswer", AnalyzeResult{}, "42", false, 2},
+		{"guided", "guided", AnalyzeResult{Math: &MathDetails{Expression: "24*15"}}, "", false, 1},
+	}
+	for _, tc := range cases {
+		calls := 0
+		srv := chatServer(leaking, tc.answer, &calls)
+		c := New(srv.URL+"/v1", "test", "chat-test")
+		got, err := c.FollowUp(context.Background(), FollowUpInput{
+			Mode: tc.mode, QuestionText: "24 × 15 = ?", Result: tc.result, Message: "孩子说是360，对吗？",
+		})
+		srv.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if redacted := !strings.Contains(got, "360") && strings.Contains(got, leakMask); redacted != tc.leaked {
+			t.Fatalf("%s: reply = %q, want redacted %v", tc.name, got, tc.leaked)
+		}
+		if calls != tc.calls {
+			t.Fatalf("%s: %d model calls, want %d", tc.name, calls, tc.calls)
+		}
+	}
+}
@@ -15,6 +15,8 @@ import (
 	oosdk "github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/shared"
+
+	"whatsdot-aibuddy/backend/internal/safety"
 )
 
 type Client struct {
@@ -22,6 +24,19 @@ type Client struct {
 	APIKey  string
 	Model   string
 	SDK     oosdk.Client
+
+	// Knowledge, when set, maps knowledge point names to canonical points.
+	Knowledge KnowledgeNormalizer
+	// Safety, when set, screens analysis results before they are returned;
+	// SafetyRegenerate re-runs an unsafe result once instead of blocking it.
+	Safety           SafetyChecker
+	SafetyRegenerate bool
+}
+
+// KnowledgeNormalizer maps free-form knowledge point names to canonical names and
+// ids (0 for names it does not know).
+type KnowledgeNormalizer interface {
+	NormalizeKnowledge(subject string, names []string) ([]string, []int64)
 }
 
 type AnalyzeResult struct {
@@ -32,6 +47,47 @@ type AnalyzeResult struct {
 	ChildStuckPoints []string `json:"child_stuck_points"`
 	KnowledgePoints  []string `json:"knowledge_points"`
 	SuggestedGrade   string   `json:"suggested_grade"`
+	// KnowledgePointIDs are the canonical ids of KnowledgePoints, 0 where unknown.
+	KnowledgePointIDs []int64 `json:"knowledge_point_ids,omitempty"`
+
+	// Legibility and Confidence are the model's own assessment of how well it could
+	// read the photo; RetakeTips are only kept when it could not. UnsureReading
+	// marks a readable photo the model had little confidence in.
+	Legibility    string   `json:"legibility"`
+	Confidence    float64  `json:"confidence"`
+	RetakeTips    []string `json:"retake_tips,omitempty"`
+	UnsureReading bool     `json:"unsure_reading,omitempty"`
+
+	// Subject is set by the server from classification; the block matching it is
+	// filled in addition to the common fields above.
+	Subject string          `json:"subject,omitempty"`
+	Math    *MathDetails    `json:"math,omitempty"`
+	Chinese *ChineseDetails `json:"chinese,omitempty"`
+	English *EnglishDetails `json:"english,omitempty"`
+
+	// Verification is the server-side arithmetic check of math results;
+	// LowConfidence is set when the answer still failed it after a re-run.
+	Verification  *Verification `json:"verification,omitempty"`
+	LowConfidence bool          `json:"low_confidence,omitempty"`
+
+	// SafetyFindings are what the safety check caught before a re-run replaced
+	// the result; kept for the incident log, never returned.
+	SafetyFindings []safety.Finding `json:"-"`
+}
+
+// DetectedQuestion is one question found on a worksheet photo in page mode.
+type DetectedQuestion struct {
+	Index        int         `json:"index"`
+	QuestionText string      `json:"question_text"`
+	BBox         BoundingBox `json:"bbox"`
+}
+
+// BoundingBox is a rectangle normalized to the image size, origin at the top-left corner.
+type BoundingBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
 }
 
 func New(baseURL, apiKey, model string) *Client {
@@ -48,26 +104,170 @@ func New(baseURL, apiKey, model string) *Client {
 	}
 }
 
+// AnalyzeInput carries the image and the knobs the analysis prompt is rendered from.
+type AnalyzeInput struct {
+	Image       []byte
+	ContentType string
+	Mode        string
+	// Focus pins the analysis to one question when the photo holds a whole page.
+	Focus string
+	// Grade is the child's actual grade; when set the explanation targets it and
+	// it replaces the model's suggested_grade guess.
+	Grade string
+	// Edition and Region name the textbook (人教版, 北师大版…) and where the child
+	// goes to school; Vocabulary lists the edition's knowledge point names to reuse.
+	Edition    string
+	Region     string
+	Vocabulary []string
+	// Subject picks the subject-specific schema; callers classify first with
+	// ClassifySubject. Empty or unknown subjects get only the common fields.
+	Subject string
+	// Language is the output language of the text fields (LanguageZhCN…); empty
+	// means DefaultLanguage.
+	Language string
+	// Transcript is a spoken question and Text a typed one; without an Image the
+	// analysis runs on them as text only.
+	Transcript string
+	Text       string
+	// CorrectedText is the parent's correction of the question on the image and
+	// is authoritative over what the model reads.
+	CorrectedText string
+}
+
 func (c *Client) AnalyzeHomework(ctx context.Context, imageBytes []byte, contentType string, mode string) (AnalyzeResult, error) {
+	return c.Analyze(ctx, AnalyzeInput{Image: imageBytes, ContentType: contentType, Mode: mode})
+}
+
+func (c *Client) Analyze(ctx context.Context, in AnalyzeInput) (AnalyzeResult, error) {
+	vars := promptVarsForMode(in.Mode)
+	vars.Focus = strings.TrimSpace(in.Focus)
+	vars.Grade = strings.TrimSpace(in.Grade)
+	vars.Edition = strings.TrimSpace(in.Edition)
+	vars.Region = strings.TrimSpace(in.Region)
+	vars.Vocabulary = strings.Join(in.Vocabulary, "、")
+	subject := NormalizeSubject(in.Subject)
+	if subject == "" {
+		subject = SubjectOther
+	}
+	vars.SubjectFields = subjectFields[subject]
+	vars.Language = languageRule(in.Language)
+	if len(in.Image) > 0 {
+		vars.Corrected = strings.TrimSpace(in.CorrectedText)
+	} else {
+		vars.QuestionText = strings.TrimSpace(in.Text)
+		if t := strings.TrimSpace(in.Transcript); t != "" {
+			vars.QuestionText, vars.Spoken = t, true
+		}
+	}
+
+	out, err := c.analyzeOnce(ctx, in, vars, subject)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+	if out.Unreadable() {
+		return out, nil
+	}
+	// Math answers are checked by evaluating the model's own expression; a mismatch
+	// gets one re-run with the correct value pointed out, then is flagged.
+	if subject == SubjectMath {
+		v := verifyMath(out)
+		if v.Status == VerifyMismatch {
+			vars.Correction = fmt.Sprintf("上一次的计算有误：算式 %s 的结果是 %s，不是 %s。请重新仔细计算，确保步骤和最终答案正确。",
+				v.Expression, v.Computed, out.Math.FinalAnswer)
+			retry, err := c.analyzeOnce(ctx, in, vars, subject)
+			if err == nil {
+				out = retry
+				v = verifyMath(out)
+			} else {
+				log.Printf("[WARN] math verification retry failed: %v", err)
+			}
+			v.Retried = true
+		}
+		out.Verification = &v
+		out.LowConfidence = v.Status == VerifyMismatch
+	}
+	if in.Mode == "noanswer" {
+		out = c.guardAnswerLeak(ctx, in, vars, subject, out)
+	}
+	if c.Safety != nil {
+		if out, err = c.guardSafety(ctx, in, vars, subject, out); err != nil {
+			return AnalyzeResult{}, err
+		}
+	}
+	return HideAnswer(out, in.Mode), nil
+}
+
+func (c *Client) analyzeOnce(ctx context.Context, in AnalyzeInput, vars promptVars, subject string) (AnalyzeResult, error) {
+	content, err := c.completeJSON(ctx, in.Mode, in.Language, renderPrompt(vars), in.Image, in.ContentType, jsonSchema{
+		Name:        "homework_analysis",
+		Description: "Homework analysis JSON for parent guidance in Chinese",
+		Schema:      analysisSchemaFor(subject),
+	})
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+
+	out, err := c.decodeAnalysis(ctx, in, subject, content)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+	out.Subject = subject
+	out = normalize(out, c.Knowledge)
+	out = normalizeSubjectDetails(out)
+	out = normalizeLegibility(out, in.Language)
+	if vars.Grade != "" {
+		out.SuggestedGrade = vars.Grade
+	}
+	return out, nil
+}
+
+// DetectQuestions lists every question found on a worksheet photo, in reading order.
+func (c *Client) DetectQuestions(ctx context.Context, imageBytes []byte, contentType string) ([]DetectedQuestion, error) {
+	content, err := c.completeJSON(ctx, "page", "", strings.TrimSpace(pagePrompt), imageBytes, contentType, jsonSchema{
+		Name:        "homework_page",
+		Description: "Questions detected on a homework page with normalized bounding boxes",
+		Schema:      pageSchema(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Questions []DetectedQuestion `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(content), &out); err != nil {
+		return nil, fmt.Errorf("invalid completion json: %w", err)
+	}
+	return normalizeQuestions(out.Questions), nil
+}
+
+type jsonSchema struct {
+	Name        string
+	Description string
+	Schema      map[string]any
+}
+
+// completeJSON sends one system+user turn with an optional image and returns the raw
+// JSON content constrained by schema. lang picks the output language of the text
+// fields, "" for the default.
+func (c *Client) completeJSON(ctx context.Context, tag, lang string, prompt string, imageBytes []byte, contentType string, schema jsonSchema) (string, error) {
 	if strings.TrimSpace(c.APIKey) == "" {
-		return AnalyzeResult{}, errors.New("OPENAI_API_KEY is empty")
+		return "", errors.New("OPENAI_API_KEY is empty")
 	}
 
-	mediaType := normalizeContentType(contentType)
-	if mediaType == "" {
-		mediaType = "image/jpeg"
+	parts := []oosdk.ChatCompletionContentPartUnionParam{oosdk.TextContentPart(prompt)}
+	mediaType := ""
+	if len(imageBytes) > 0 {
+		var part oosdk.ChatCompletionContentPartUnionParam
+		part, mediaType = imagePart(imageBytes, contentType)
+		parts = append(parts, part)
 	}
-	imageDataURL := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(imageBytes)
-	prompt := modePrompt(mode)
-	log.Printf("[OPENAI_REQ] endpoint=%s domain=%s model=%s mode=%s content_type=%s image_bytes=%d prompt=%q",
-		c.BaseURL, extractDomain(c.BaseURL), c.Model, mode, mediaType, len(imageBytes), prompt)
+	log.Printf("[OPENAI_REQ] endpoint=%s domain=%s model=%s mode=%s schema=%s content_type=%s image_bytes=%d prompt=%q",
+		c.BaseURL, extractDomain(c.BaseURL), c.Model, tag, schema.Name, mediaType, len(imageBytes), prompt)
 
 	messages := []oosdk.ChatCompletionMessageParamUnion{
-		oosdk.SystemMessage(systemPrompt()),
-		oosdk.UserMessage([]oosdk.ChatCompletionContentPartUnionParam{
-			oosdk.TextContentPart(prompt),
-			oosdk.ImageContentPart(oosdk.ChatCompletionContentPartImageImageURLParam{URL: imageDataURL, Detail: "high"}),
-		}),
+		oosdk.SystemMessage(systemPrompt(lang)),
+		oosdk.UserMessage(parts),
 	}
 
 	resp, err := c.SDK.Chat.Completions.New(ctx, oosdk.ChatCompletionNewParams{
@@ -76,10 +276,10 @@ func (c *Client) AnalyzeHomework(ctx context.Context, imageBytes []byte, content
 		ResponseFormat: oosdk.ChatCompletionNewParamsResponseFormatUnion{
 			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
 				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
-					Name:        "homework_analysis",
-					Description: oosdk.String("Homework analysis JSON for parent guidance in Chinese"),
+					Name:        schema.Name,
+					Description: oosdk.String(schema.Description),
 					Strict:      oosdk.Bool(true),
-					Schema:      analysisSchema(),
+					Schema:      schema.Schema,
 				},
 			},
 		},
@@ -87,25 +287,30 @@ func (c *Client) AnalyzeHomework(ctx context.Context, imageBytes []byte, content
 	})
 	if err != nil {
 		log.Printf("[OPENAI_ERR] endpoint=%s domain=%s model=%s mode=%s err=%v",
-			c.BaseURL, extractDomain(c.BaseURL), c.Model, mode, err)
-		return AnalyzeResult{}, fmt.Errorf("chat completion failed: %w", err)
+			c.BaseURL, extractDomain(c.BaseURL), c.Model, tag, err)
+		return "", fmt.Errorf("chat completion failed: %w", err)
 	}
 	log.Printf("[OPENAI_RESP] request_id=%s model=%s prompt_tokens=%d completion_tokens=%d total_tokens=%d",
 		resp.ID, resp.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, resp.Usage.TotalTokens)
 	if len(resp.Choices) == 0 {
-		return AnalyzeResult{}, errors.New("empty choices")
+		return "", errors.New("empty choices")
 	}
 
 	content := strings.TrimSpace(resp.Choices[0].Message.Content)
 	if content == "" {
-		return AnalyzeResult{}, errors.New("empty completion content")
+		return "", errors.New("empty completion content")
 	}
+	return extractJSON(content), nil
+}
 
-	var out AnalyzeResult
-	if err := json.Unmarshal([]byte(content), &out); err != nil {
-		return AnalyzeResult{}, fmt.Errorf("invalid completion json: %w", err)
+// imagePart inlines an image as a data URL content part.
+func imagePart(imageBytes []byte, contentType string) (oosdk.ChatCompletionContentPartUnionParam, string) {
+	mediaType := normalizeContentType(contentType)
+	if mediaType == "" {
+		mediaType = "image/jpeg"
 	}
-	return normalize(out), nil
+	imageDataURL := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(imageBytes)
+	return oosdk.ImageContentPart(oosdk.ChatCompletionContentPartImageImageURLParam{URL: imageDataURL, Detail: "high"}), mediaType
 }
 
 func extractDomain(rawBaseURL string) string {
@@ -116,7 +321,7 @@ func extractDomain(rawBaseURL string) string {
 	return u.Host
 }
 
-func normalize(input AnalyzeResult) AnalyzeResult {
+func normalize(input AnalyzeResult, kn KnowledgeNormalizer) AnalyzeResult {
 	out := input
 	out.QuestionText = strings.TrimSpace(out.QuestionText)
 	out.SolutionThoughts = strings.TrimSpace(out.SolutionThoughts)
@@ -128,9 +333,50 @@ func normalize(input AnalyzeResult) AnalyzeResult {
 	if len(out.ChildStuckPoints) > 2 {
 		out.ChildStuckPoints = out.ChildStuckPoints[:2]
 	}
+	if kn != nil {
+		out.KnowledgePoints, out.KnowledgePointIDs = kn.NormalizeKnowledge(out.Subject, out.KnowledgePoints)
+	}
+	return out
+}
+
+func normalizeQuestions(input []DetectedQuestion) []DetectedQuestion {
+	out := make([]DetectedQuestion, 0, len(input))
+	for _, q := range input {
+		q.QuestionText = strings.TrimSpace(q.QuestionText)
+		if q.QuestionText == "" {
+			continue
+		}
+		q.BBox = q.BBox.Clamp()
+		q.Index = len(out) + 1
+		out = append(out, q)
+	}
 	return out
 }
 
+// Clamp keeps the rectangle inside the unit square.
+func (b BoundingBox) Clamp() BoundingBox {
+	clamp := func(v float64) float64 {
+		if v < 0 {
+			return 0
+		}
+		if v > 1 {
+			return 1
+		}
+		return v
+	}
+	b.X = clamp(b.X)
+	b.Y = clamp(b.Y)
+	b.Width = clamp(b.Width)
+	b.Height = clamp(b.Height)
+	if b.X+b.Width > 1 {
+		b.Width = 1 - b.X
+	}
+	if b.Y+b.Height > 1 {
+		b.Height = 1 - b.Y
+	}
+	return b
+}
+
 func normalizeContentType(contentType string) string {
 	contentType = strings.TrimSpace(contentType)
 	if contentType == "" {
@@ -143,10 +389,10 @@ func normalizeContentType(contentType string) string {
 	return mediaType
 }
 
-func systemPrompt() string {
+func systemPrompt(lang string) string {
 	return "你是一名有耐心的小学家庭学习教练和家长沟通顾问。" +
 		"你的目标不是替孩子做题，而是帮助家长通过提问让孩子自己思考。" +
-		"输出必须是严格 JSON，不能输出 markdown、不能输出解释文字。"
+		"输出必须是严格 JSON，不能输出 markdown、不能输出解释文字。" + languageRule(lang)
 }
 
 func analysisSchema() map[string]any {
@@ -161,6 +407,9 @@ func analysisSchema() map[string]any {
 			"child_stuck_points",
 			"knowledge_points",
 			"suggested_grade",
+			"legibility",
+			"confidence",
+			"retake_tips",
 		},
 		"properties": map[string]any{
 			"question_text":     map[string]any{"type": "string"},
@@ -179,6 +428,45 @@ func analysisSchema() map[string]any {
 				"items": map[string]any{"type": "string"},
 			},
 			"suggested_grade": map[string]any{"type": "string"},
+			"legibility": map[string]any{
+				"type": "string",
+				"enum": []string{LegibilityClear, LegibilityPartial, LegibilityUnreadable},
+			},
+			"confidence": map[string]any{"type": "number"},
+			"retake_tips": map[string]any{
+				"type": "array", "minItems": 0, "maxItems": 3,
+				"items": map[string]any{"type": "string"},
+			},
+		},
+	}
+}
+
+func pageSchema() map[string]any {
+	number := map[string]any{"type": "number"}
+	return map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"questions"},
+		"properties": map[string]any{
+			"questions": map[string]any{
+				"type": "array", "minItems": 1, "maxItems": 30,
+				"items": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"required":             []string{"question_text", "bbox"},
+					"properties": map[string]any{
+						"question_text": map[string]any{"type": "string"},
+						"bbox": map[string]any{
+							"type":                 "object",
+							"additionalProperties": false,
+							"required":             []string{"x", "y", "width", "height"},
+							"properties": map[string]any{
+								"x": number, "y": number, "width": number, "height": number,
+							},
+						},
+					},
+				},
+			},
 		},
 	}
 }
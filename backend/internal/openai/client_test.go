@@ -1,8 +1,11 @@
 package openai
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+
+	"whatsdot-aibuddy/backend/internal/answer"
 )
 
 func TestModePromptContainsStructuredExperienceGuidance(t *testing.T) {
@@ -52,3 +55,310 @@ func TestModePromptIsRenderedFromTemplateVariables(t *testing.T) {
 		t.Fatalf("expected mode label to be injected into prompt, got: %s", p)
 	}
 }
+
+func TestModePromptFocusesOnSelectedQuestion(t *testing.T) {
+	v := promptVarsForMode("guided")
+	v.Focus = "36 × 25 = ?"
+	p := renderPrompt(v)
+	if !strings.Contains(p, "只分析下面这一道") || !strings.Contains(p, "36 × 25 = ?") {
+		t.Fatalf("expected focus question in prompt, got: %s", p)
+	}
+	if strings.Contains(modePrompt("guided"), "只分析下面这一道") {
+		t.Fatalf("prompt without focus should not mention a selected question")
+	}
+}
+
+func TestModePromptUsesChildGrade(t *testing.T) {
+	v := promptVarsForMode("detailed")
+	v.Grade = "二年级"
+	p := renderPrompt(v)
+	if !strings.Contains(p, "孩子现在读二年级") || !strings.Contains(p, "suggested_grade 填写“二年级”") {
+		t.Fatalf("expected child grade in prompt, got: %s", p)
+	}
+	if strings.Contains(modePrompt("detailed"), "孩子现在读") {
+		t.Fatalf("prompt without grade should not mention the child's grade")
+	}
+}
+
+func TestModePromptUsesCurriculum(t *testing.T) {
+	v := promptVarsForMode("guided")
+	v.Edition = "北师大版"
+	v.Region = "成都"
+	v.Vocabulary = "运算律、乘法分配律"
+	p := renderPrompt(v)
+	for _, want := range []string{"使用北师大版教材", "在成都上学", "运算律、乘法分配律"} {
+		if !strings.Contains(p, want) {
+			t.Fatalf("expected %q in prompt, got: %s", want, p)
+		}
+	}
+}
+
+func TestAnalysisSchemaForSubjectAddsBlock(t *testing.T) {
+	schema := analysisSchemaFor(SubjectMath)
+	required := schema["required"].([]string)
+	if required[len(required)-1] != SubjectMath {
+		t.Fatalf("expected math block to be required, got %v", required)
+	}
+	if _, ok := schema["properties"].(map[string]any)[SubjectMath]; !ok {
+		t.Fatalf("expected math block in properties")
+	}
+	if len(analysisSchema()["required"].([]string)) != len(required)-1 {
+		t.Fatalf("subject schema must not modify the common schema")
+	}
+	if _, ok := analysisSchemaFor(SubjectOther)["properties"].(map[string]any)[SubjectOther]; ok {
+		t.Fatalf("other subject should only have common fields")
+	}
+}
+
+func TestNormalizeSubjectDetails(t *testing.T) {
+	out := normalizeSubjectDetails(AnalyzeResult{
+		Subject: SubjectMath,
+		Math:    &MathDetails{Steps: []string{" 24×10=240 ", "", "240+120=360"}, FinalAnswer: "360"},
+		English: &EnglishDetails{GrammarPoint: "x"},
+	})
+	out = HideAnswer(out, "noanswer")
+	if out.English != nil {
+		t.Fatalf("expected block of another subject to be dropped")
+	}
+	if len(out.Math.Steps) != 1 || out.Math.Steps[0] != "24×10=240" {
+		t.Fatalf("noanswer mode must drop the step reaching the answer, got %v", out.Math.Steps)
+	}
+	if out.Math.FinalAnswer != "" {
+		t.Fatalf("noanswer mode must not carry the final answer, got %q", out.Math.FinalAnswer)
+	}
+	if NormalizeSubject("语文") != SubjectChinese || NormalizeSubject("art") != "" {
+		t.Fatalf("unexpected subject normalization")
+	}
+}
+
+func TestVerifyMath(t *testing.T) {
+	cases := []struct {
+		expression, answer, status string
+	}{
+		{"24*15", "360", VerifyPassed},
+		{"24 × 15", "360个", VerifyPassed},
+		{"48/6", "7", VerifyMismatch},
+		{"", "360", VerifyUnchecked},
+		{"24*15", "三百六十", VerifyUnchecked},
+		{"24*15=360", "360", VerifyPassed},
+		{"48/7", "6余6", VerifyUnchecked},
+		{"48÷7", "6……6", VerifyUnchecked},
+	}
+	for _, tc := range cases {
+		v := verifyMath(AnalyzeResult{Subject: SubjectMath, Math: &MathDetails{Expression: tc.expression, FinalAnswer: tc.answer}})
+		if v.Status != tc.status {
+			t.Fatalf("verifyMath(%q, %q) = %s, want %s", tc.expression, tc.answer, v.Status, tc.status)
+		}
+	}
+	if v := verifyMath(AnalyzeResult{Math: &MathDetails{Expression: "48/6", FinalAnswer: "7"}}); v.Computed != "8" {
+		t.Fatalf("expected computed value on mismatch, got %q", v.Computed)
+	}
+}
+
+func TestNormalizeLegibility(t *testing.T) {
+	out := normalizeLegibility(AnalyzeResult{Legibility: " Unreadable ", Confidence: 0.8}, "")
+	if !out.Unreadable() || !reflect.DeepEqual(out.RetakeTips, defaultRetakeTips[LanguageZhCN]) {
+		t.Fatalf("expected unreadable result with default tips, got %+v", out)
+	}
+	out = normalizeLegibility(AnalyzeResult{Legibility: "unreadable"}, "en-US")
+	if !out.Unreadable() || !reflect.DeepEqual(out.RetakeTips, defaultRetakeTips[LanguageEN]) {
+		t.Fatalf("expected english default tips, got %+v", out.RetakeTips)
+	}
+	out = normalizeLegibility(AnalyzeResult{Legibility: "unreadable", Confidence: 0.1, RetakeTips: []string{" 开灯 ", ""}}, "")
+	if !out.Unreadable() || out.UnsureReading || len(out.RetakeTips) != 1 || out.RetakeTips[0] != "开灯" {
+		t.Fatalf("expected trimmed model tips, got %+v", out)
+	}
+	out = normalizeLegibility(AnalyzeResult{Legibility: "clear", Confidence: 0.1, RetakeTips: []string{"开灯"}}, "")
+	if out.Unreadable() || !out.UnsureReading || out.RetakeTips != nil {
+		t.Fatalf("expected low confidence on a legible photo to be flagged only, got %+v", out)
+	}
+	out = normalizeLegibility(AnalyzeResult{Legibility: "blurry", Confidence: 1.7, RetakeTips: []string{"开灯"}}, "")
+	if out.Unreadable() || out.UnsureReading || out.Legibility != LegibilityPartial || out.Confidence != 1 || out.RetakeTips != nil {
+		t.Fatalf("unexpected normalization: %+v", out)
+	}
+}
+
+func TestAnswerLeakDetectionAndRedaction(t *testing.T) {
+	out := AnalyzeResult{
+		QuestionText:     "24 × 15 = ?",
+		SolutionThoughts: "先算 24×10=240，再算 24×5=120，合起来是360。",
+		ExplainToChild:   "你觉得两部分加起来是多少？",
+		ParentGuidance:   []string{"答案是三百六十吗？"},
+		Math:             &MathDetails{Steps: []string{"240+120=360"}, FinalAnswer: "360", Expression: "24*15"},
+	}
+	variants := leakVariants("360", out.QuestionText)
+	if !resultLeaks(out, variants) {
+		t.Fatalf("expected leak in result")
+	}
+	red := mapText(out, func(s string) string { return answer.Redact(s, variants, leakMask) })
+	if resultLeaks(red, variants) {
+		t.Fatalf("expected redacted result not to leak: %+v", red)
+	}
+	if red.Math.FinalAnswer != "360" || out.Math.Steps[0] != "240+120=360" {
+		t.Fatalf("redaction must leave the final answer field and the original alone")
+	}
+	if got := leakVariants("15", out.QuestionText); len(got) != 1 || got[0] != "十五" {
+		t.Fatalf("forms present in the question should be skipped, got %v", got)
+	}
+}
+
+func TestResultTextsMarkAloudFields(t *testing.T) {
+	out := AnalyzeResult{
+		QuestionText:   "课文里写他被打死了",
+		ExplainToChild: "我们一起读一读",
+		ParentGuidance: []string{"", "问问孩子"},
+		Chinese:        &ChineseDetails{PassageSummary: "讲了一个故事", KeySentences: []string{"他被打死了"}},
+	}
+	got := resultTexts(out)
+	fields := make([]string, len(got))
+	for i, t := range got {
+		fields[i] = t.Field
+		if t.Aloud {
+			fields[i] += "!"
+		}
+	}
+	want := "question_text,explain_to_child!,parent_guidance[1]!,chinese.passage_summary,chinese.key_sentences[0]"
+	if strings.Join(fields, ",") != want {
+		t.Fatalf("fields = %v, want %s", fields, want)
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	cases := map[string]string{
+		`{"a":1}`:                 `{"a":1}`,
+		"```json\n{\"a\":1}\n```": `{"a":1}`,
+		"好的，结果如下：\n{\"a\":{\"b\":2}}\n希望有帮助": `{"a":{"b":2}}`,
+		"no json here": "no json here",
+	}
+	for in, want := range cases {
+		if got := extractJSON(in); got != want {
+			t.Fatalf("extractJSON(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidateAnalysis(t *testing.T) {
+	obj := map[string]any{
+		"question_text":      "",
+		"solution_thoughts":  "先算十位",
+		"explain_to_child":   " ",
+		"parent_guidance":    []any{"a", "b"},
+		"child_stuck_points": []any{"x", ""},
+		"knowledge_points":   []any{"乘法", "口算"},
+		"suggested_grade":    "三年级",
+		"legibility":         "clear",
+		"confidence":         0.9,
+		"retake_tips":        []any{},
+		"math":               map[string]any{"steps": []any{"24*15"}, "final_answer": "360", "expression": ""},
+		"note":               "extra",
+	}
+	got := validateAnalysis(obj, analysisSchemaFor(SubjectMath))
+	want := []Violation{
+		{Path: "explain_to_child", Reason: "empty"},
+		{Path: "parent_guidance", Reason: "want at least 3 items, got 2"},
+		{Path: "child_stuck_points[1]", Reason: "empty"},
+		{Path: "note", Reason: "unexpected field"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("violations = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("violation %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	rest := dropUnexpected(obj, got)
+	if _, ok := obj["note"]; ok || len(rest) != 3 {
+		t.Fatalf("unexpected field should be dropped locally, rest = %+v", rest)
+	}
+	if fields := violatingFields(rest); strings.Join(fields, ",") != "explain_to_child,parent_guidance,child_stuck_points" {
+		t.Fatalf("violating fields = %v", fields)
+	}
+
+	obj["legibility"] = LegibilityUnreadable
+	if got := validateAnalysis(obj, analysisSchemaFor(SubjectMath)); len(got) != 0 {
+		t.Fatalf("unreadable results only need the right shape, got %+v", got)
+	}
+	obj["confidence"] = "high"
+	if got := validateAnalysis(obj, analysisSchemaFor(SubjectMath)); len(got) != 1 || got[0].Path != "confidence" {
+		t.Fatalf("expected type violation, got %+v", got)
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":        "",
+		"zh":      LanguageZhCN,
+		"zh_CN":   LanguageZhCN,
+		"zh-Hans": LanguageZhCN,
+		"zh-TW":   LanguageZhTW,
+		"zh-HK":   LanguageZhTW,
+		"zh-Hant": LanguageZhTW,
+		"EN-us":   LanguageEN,
+		"fr":      "",
+	}
+	for in, want := range cases {
+		if got := NormalizeLanguage(in); got != want {
+			t.Fatalf("NormalizeLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPromptLanguageRule(t *testing.T) {
+	v := promptVarsForMode("guided")
+	if p := renderPrompt(v); strings.Contains(p, "输出语言") {
+		t.Fatalf("default prompt should not carry a language rule")
+	}
+	v.Language = languageRule("en-GB")
+	if p := renderPrompt(v); !strings.Contains(p, "英文书写") {
+		t.Fatalf("english prompt should carry the language rule, got %q", p)
+	}
+	if !strings.Contains(systemPrompt(LanguageZhTW), "繁体中文") || strings.Contains(systemPrompt(""), "输出语言") {
+		t.Fatalf("system prompt should follow the language")
+	}
+	if !strings.Contains(weeklyReportSystemPrompt(LanguageEN), "回答用英文") || !strings.Contains(weeklyReportSystemPrompt(""), "回答用中文") {
+		t.Fatalf("weekly report prompt should follow the language")
+	}
+}
+
+func TestSpokenPromptReplacesPhotoInstructions(t *testing.T) {
+	v := promptVarsForMode("guided")
+	v.QuestionText, v.Spoken = "二十四乘十五等于多少", true
+	p := renderPrompt(v)
+	if !strings.Contains(p, "二十四乘十五等于多少") || strings.Contains(p, "请你先阅读图片中的题目") {
+		t.Fatalf("spoken prompt should carry the transcript instead of the photo instructions: %q", p)
+	}
+	if p := modePrompt("guided"); !strings.Contains(p, "请你先阅读图片中的题目") {
+		t.Fatalf("photo prompt lost its instructions")
+	}
+	v.Spoken = false
+	if p := renderPrompt(v); !strings.Contains(p, "家长输入的文字") || strings.Contains(p, "重新录音") {
+		t.Fatalf("typed prompt should not talk about recordings: %q", p)
+	}
+}
+
+func TestCorrectedTextIsAuthoritative(t *testing.T) {
+	v := promptVarsForMode("guided")
+	v.Corrected = "24 × 16 = ?"
+	p := renderPrompt(v)
+	if !strings.Contains(p, "以下面的文字为准") || !strings.Contains(p, "24 × 16 = ?") || !strings.Contains(p, "请你先阅读图片中的题目") {
+		t.Fatalf("corrected prompt should keep the image and pin the text: %q", p)
+	}
+}
+
+func TestNormalizeQuestionsReindexesAndClamps(t *testing.T) {
+	got := normalizeQuestions([]DetectedQuestion{
+		{Index: 7, QuestionText: "  ", BBox: BoundingBox{}},
+		{Index: 9, QuestionText: " 24 × 15 = ? ", BBox: BoundingBox{X: -0.1, Y: 0.8, Width: 0.5, Height: 0.5}},
+	})
+	if len(got) != 1 {
+		t.Fatalf("expected empty question to be dropped, got %d", len(got))
+	}
+	q := got[0]
+	if q.Index != 1 || q.QuestionText != "24 × 15 = ?" {
+		t.Fatalf("unexpected question: %+v", q)
+	}
+	if q.BBox.X != 0 || q.BBox.Y+q.BBox.Height > 1.0000001 {
+		t.Fatalf("expected bbox clamped to unit square, got %+v", q.BBox)
+	}
+}
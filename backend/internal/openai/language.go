@@ -0,0 +1,56 @@
+package openai
+
+import "strings"
+
+// Output languages. Question text always stays as written on the homework.
+const (
+	LanguageZhCN = "zh-CN"
+	LanguageZhTW = "zh-TW"
+	LanguageEN   = "en"
+)
+
+// DefaultLanguage is used when neither the request nor the device picks one.
+const DefaultLanguage = LanguageZhCN
+
+// NormalizeLanguage maps language tags such as "zh_TW", "zh-Hant", "zh-HK" or
+// "en-US" to a supported output language, or "" when unsupported.
+func NormalizeLanguage(tag string) string {
+	t := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	switch {
+	case t == "":
+		return ""
+	case t == "en" || strings.HasPrefix(t, "en-"):
+		return LanguageEN
+	case t == "zh-tw" || t == "zh-hk" || t == "zh-mo" || strings.HasPrefix(t, "zh-hant"):
+		return LanguageZhTW
+	case t == "zh" || strings.HasPrefix(t, "zh-"):
+		return LanguageZhCN
+	}
+	return ""
+}
+
+// replyLanguage names the language of free-form replies such as follow-up
+// answers and the rehearsal child's lines.
+func replyLanguage(lang string) string {
+	switch NormalizeLanguage(lang) {
+	case LanguageEN:
+		return "英文"
+	case LanguageZhTW:
+		return "繁体中文（台湾用语）"
+	}
+	return "中文"
+}
+
+// languageRule tells the model which language to write in; empty for the default.
+// Knowledge point names stay in simplified Chinese because they are matched
+// against the curriculum taxonomy.
+func languageRule(lang string) string {
+	switch NormalizeLanguage(lang) {
+	case LanguageEN:
+		return "输出语言：除 question_text 保持题目原文、knowledge_points 使用简体中文知识点名称、legibility 等枚举值保持原样外，其余文字字段全部用英文书写。" +
+			"家长是讲英文的，孩子在中文学校上学：讲解中的关键中文术语可在英文后用括号附上原文，方便家长和孩子对照。"
+	case LanguageZhTW:
+		return "输出语言：除 question_text 保持题目原文、knowledge_points 使用简体中文知识点名称、legibility 等枚举值保持原样外，其余文字字段全部用繁体中文（台湾用语）书写。"
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"strings"
+
+	"whatsdot-aibuddy/backend/internal/answer"
+	"whatsdot-aibuddy/backend/internal/expr"
+)
+
+// leakStats counts noanswer results by outcome of the answer-leak check; the leak
+// rate is leaked/checked. Exposed through expvar.
+var leakStats = expvar.NewMap("noanswer_leak")
+
+const leakMask = "□"
+
+// guardAnswerLeak makes sure a noanswer result does not reveal the final answer.
+// The answer comes from the math block or a separate call and never leaves the
+// server. A leaking result is regenerated once, then redacted.
+func (c *Client) guardAnswerLeak(ctx context.Context, in AnalyzeInput, vars promptVars, subject string, out AnalyzeResult) AnalyzeResult {
+	final := ""
+	if out.Math != nil {
+		final = out.Math.FinalAnswer
+	}
+	if final == "" {
+		var err error
+		if final, err = c.solveAnswer(ctx, in, out.QuestionText); err != nil {
+			log.Printf("[WARN] noanswer leak check skipped: %v", err)
+			leakStats.Add("unchecked", 1)
+			return out
+		}
+	}
+	variants := leakVariants(final, out.QuestionText)
+	if len(variants) == 0 {
+		leakStats.Add("unchecked", 1)
+		return out
+	}
+	leakStats.Add("checked", 1)
+	if !resultLeaks(out, variants) {
+		return out
+	}
+	leakStats.Add("leaked", 1)
+	log.Printf("[WARN] noanswer result leaked the answer, regenerating")
+
+	vars.Correction = strings.TrimSpace(vars.Correction + "\n上一次的输出透露了最终答案，不符合不给答案模式。除 math.final_answer 外，任何字段都不能出现最终答案或它的其他写法（数字、中文数字、分数、小数），只给方向和提示问题。")
+	retry, err := c.analyzeOnce(ctx, in, vars, subject)
+	if err == nil && !retry.Unreadable() {
+		retry.Verification, retry.LowConfidence = out.Verification, out.LowConfidence
+		if !resultLeaks(retry, variants) {
+			leakStats.Add("regenerated", 1)
+			return retry
+		}
+		out = retry
+	} else if err != nil {
+		log.Printf("[WARN] noanswer regenerate failed: %v", err)
+	}
+	leakStats.Add("redacted", 1)
+	return mapText(out, func(s string) string { return answer.Redact(s, variants, leakMask) })
+}
+
+// redactReplyLeak masks the final answer in a noanswer follow-up reply. The answer
+// is the record's check expression when it evaluates, otherwise a separate call;
+// stored noanswer results no longer carry it.
+func (c *Client) redactReplyLeak(ctx context.Context, in FollowUpInput, reply string) string {
+	final := ""
+	if in.Result.Math != nil && strings.TrimSpace(in.Result.Math.Expression) != "" {
+		if v, err := expr.Eval(in.Result.Math.Expression); err == nil {
+			final = formatRat(v.RatString())
+		}
+	}
+	if final == "" {
+		var err error
+		a := AnalyzeInput{Image: in.Image, ContentType: in.ContentType, Language: in.Language}
+		if final, err = c.solveAnswer(ctx, a, in.QuestionText); err != nil {
+			log.Printf("[WARN] noanswer follow-up leak check skipped: %v", err)
+			leakStats.Add("followup_unchecked", 1)
+			return reply
+		}
+	}
+	variants := leakVariants(final, in.QuestionText)
+	if len(variants) == 0 || !answer.FindLeak(reply, variants) {
+		return reply
+	}
+	leakStats.Add("followup_redacted", 1)
+	log.Printf("[WARN] noanswer follow-up leaked the answer, redacting")
+	return answer.Redact(reply, variants, leakMask)
+}
+
+// leakVariants are the answer's written forms worth looking for. Forms that also
+// appear in the question cannot be told apart from restating it and are skipped.
+func leakVariants(final, questionText string) []string {
+	var out []string
+	for _, v := range answer.Variants(final) {
+		if !answer.FindLeak(questionText, []string{v}) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func resultLeaks(out AnalyzeResult, variants []string) bool {
+	leaked := false
+	mapText(out, func(s string) string {
+		if !leaked && answer.FindLeak(s, variants) {
+			leaked = true
+		}
+		return s
+	})
+	return leaked
+}
+
+// mapText applies fn to every free-text field shown to the family, leaving the
+// question text, the final answer and the check expression alone.
+func mapText(out AnalyzeResult, fn func(string) string) AnalyzeResult {
+	list := func(in []string) []string {
+		if in == nil {
+			return nil
+		}
+		res := make([]string, len(in))
+		for i, s := range in {
+			res[i] = fn(s)
+		}
+		return res
+	}
+	out.SolutionThoughts = fn(out.SolutionThoughts)
+	out.ExplainToChild = fn(out.ExplainToChild)
+	out.ParentGuidance = list(out.ParentGuidance)
+	out.ChildStuckPoints = list(out.ChildStuckPoints)
+	out.KnowledgePoints = list(out.KnowledgePoints)
+	if out.Math != nil {
+		m := *out.Math
+		m.Steps = list(m.Steps)
+		out.Math = &m
+	}
+	if out.Chinese != nil {
+		ch := *out.Chinese
+		ch.PassageSummary = fn(ch.PassageSummary)
+		ch.KeySentences = list(ch.KeySentences)
+		out.Chinese = &ch
+	}
+	if out.English != nil {
+		en := *out.English
+		en.GrammarPoint = fn(en.GrammarPoint)
+		vocab := make([]VocabularyItem, len(en.Vocabulary))
+		for i, v := range en.Vocabulary {
+			vocab[i] = VocabularyItem{Word: fn(v.Word), Meaning: fn(v.Meaning)}
+		}
+		en.Vocabulary = vocab
+		out.English = &en
+	}
+	return out
+}
+
+// solveAnswer asks for just the final answer of the question, for subjects whose
+// schema does not carry one.
+func (c *Client) solveAnswer(ctx context.Context, in AnalyzeInput, questionText string) (string, error) {
+	prompt := fmt.Sprintf(strings.TrimSpace(answerOnlyPrompt), questionText)
+	content, err := c.completeJSON(ctx, "answer", in.Language, prompt, in.Image, in.ContentType, jsonSchema{
+		Name:        "homework_answer",
+		Description: "Final answer of a homework question, kept server-side",
+		Schema: map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []string{"final_answer"},
+			"properties":           map[string]any{"final_answer": map[string]any{"type": "string"}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		FinalAnswer string `json:"final_answer"`
+	}
+	if err := json.Unmarshal([]byte(content), &out); err != nil {
+		return "", fmt.Errorf("invalid completion json: %w", err)
+	}
+	return strings.TrimSpace(out.FinalAnswer), nil
+}
@@ -0,0 +1,99 @@
+package openai
+
+import "strings"
+
+// Legibility levels of the homework photo, as judged by the model.
+const (
+	LegibilityClear      = "clear"
+	LegibilityPartial    = "partial"
+	LegibilityUnreadable = "unreadable"
+)
+
+// minConfidence is the confidence below which a readable result is flagged as an
+// unsure reading for the parent to double-check.
+const minConfidence = 0.3
+
+// defaultRetakeTips are shown when the model gives no suggestions of its own,
+// keyed by output language.
+var defaultRetakeTips = map[string][]string{
+	LanguageZhCN: {
+		"在光线充足的地方拍，避免阴影和反光。",
+		"把题目放在画面中间，尽量拍正、拍全。",
+		"拿稳手机，等画面对焦清晰后再拍。",
+	},
+	LanguageZhTW: {
+		"在光線充足的地方拍，避免陰影和反光。",
+		"把題目放在畫面中間，盡量拍正、拍全。",
+		"拿穩手機，等畫面對焦清晰後再拍。",
+	},
+	LanguageEN: {
+		"Take the photo in good light, avoiding shadows and glare.",
+		"Keep the question in the middle of the frame, straight and complete.",
+		"Hold the phone steady and wait for the picture to focus.",
+	},
+}
+
+// rerecordTips replace the photo tips when nothing could be heard in a recording.
+var rerecordTips = map[string][]string{
+	LanguageZhCN: {
+		"在安静的地方录音，靠近手机说话。",
+		"把题目完整、清楚地念一遍。",
+	},
+	LanguageZhTW: {
+		"在安靜的地方錄音，靠近手機說話。",
+		"把題目完整、清楚地念一遍。",
+	},
+	LanguageEN: {
+		"Record somewhere quiet and speak close to the phone.",
+		"Read the whole question out clearly.",
+	},
+}
+
+// RerecordTips are the suggestions for an inaudible recording in lang.
+func RerecordTips(lang string) []string {
+	tips, ok := rerecordTips[NormalizeLanguage(lang)]
+	if !ok {
+		tips = rerecordTips[DefaultLanguage]
+	}
+	return append([]string(nil), tips...)
+}
+
+func retakeTips(lang string) []string {
+	tips, ok := defaultRetakeTips[NormalizeLanguage(lang)]
+	if !ok {
+		tips = defaultRetakeTips[DefaultLanguage]
+	}
+	return append([]string(nil), tips...)
+}
+
+// Unreadable reports whether the question could not be read from the photo, in
+// which case the rest of the result must not be trusted.
+func (r AnalyzeResult) Unreadable() bool {
+	return r.Legibility == LegibilityUnreadable
+}
+
+// normalizeLegibility keeps the legibility fields in range and fills retake tips
+// in lang for unreadable photos.
+func normalizeLegibility(out AnalyzeResult, lang string) AnalyzeResult {
+	switch l := strings.ToLower(strings.TrimSpace(out.Legibility)); l {
+	case LegibilityClear, LegibilityPartial, LegibilityUnreadable:
+		out.Legibility = l
+	default:
+		out.Legibility = LegibilityPartial
+	}
+	if out.Confidence < 0 {
+		out.Confidence = 0
+	}
+	if out.Confidence > 1 {
+		out.Confidence = 1
+	}
+	out.RetakeTips = trimList(out.RetakeTips, 3)
+	if out.Unreadable() && len(out.RetakeTips) == 0 {
+		out.RetakeTips = retakeTips(lang)
+	}
+	if !out.Unreadable() {
+		out.RetakeTips = nil
+	}
+	out.UnsureReading = !out.Unreadable() && out.Confidence < minConfidence
+	return out
+}
@@ -0,0 +1,103 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PracticeProblem is a generated variant of an analyzed question.
+type PracticeProblem struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	Hint     string `json:"hint"`
+}
+
+// PracticeInput describes the analyzed question the variants are based on.
+type PracticeInput struct {
+	QuestionText string
+	Result       AnalyzeResult
+	Count        int
+	// Language is the output language of the problems, answers and hints.
+	Language string
+}
+
+const (
+	minPracticeCount = 3
+	maxPracticeCount = 5
+)
+
+// GeneratePractice creates variant problems at the same difficulty and knowledge points.
+func (c *Client) GeneratePractice(ctx context.Context, in PracticeInput) ([]PracticeProblem, error) {
+	count := ClampPracticeCount(in.Count)
+	prompt := fmt.Sprintf(strings.TrimSpace(practicePrompt),
+		in.QuestionText, strings.Join(in.Result.KnowledgePoints, "、"), in.Result.SuggestedGrade, count)
+
+	content, err := c.completeJSON(ctx, "practice", in.Language, prompt, nil, "", jsonSchema{
+		Name:        "practice_problems",
+		Description: "Variant practice problems with answers and hints",
+		Schema:      practiceSchema(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Problems []PracticeProblem `json:"problems"`
+	}
+	if err := json.Unmarshal([]byte(content), &out); err != nil {
+		return nil, fmt.Errorf("invalid completion json: %w", err)
+	}
+
+	problems := make([]PracticeProblem, 0, len(out.Problems))
+	for _, p := range out.Problems {
+		p.Question = strings.TrimSpace(p.Question)
+		p.Answer = strings.TrimSpace(p.Answer)
+		p.Hint = strings.TrimSpace(p.Hint)
+		if p.Question == "" || p.Answer == "" {
+			continue
+		}
+		problems = append(problems, p)
+	}
+	if len(problems) > count {
+		problems = problems[:count]
+	}
+	if len(problems) == 0 {
+		return nil, fmt.Errorf("no practice problems generated")
+	}
+	return problems, nil
+}
+
+// ClampPracticeCount keeps the requested number of problems within 3-5, defaulting to 3.
+func ClampPracticeCount(n int) int {
+	if n < minPracticeCount {
+		return minPracticeCount
+	}
+	if n > maxPracticeCount {
+		return maxPracticeCount
+	}
+	return n
+}
+
+func practiceSchema() map[string]any {
+	return map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"problems"},
+		"properties": map[string]any{
+			"problems": map[string]any{
+				"type": "array", "minItems": minPracticeCount, "maxItems": maxPracticeCount,
+				"items": map[string]any{
+					"type":                 "object",
+					"additionalProperties": false,
+					"required":             []string{"question", "answer", "hint"},
+					"properties": map[string]any{
+						"question": map[string]any{"type": "string"},
+						"answer":   map[string]any{"type": "string"},
+						"hint":     map[string]any{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
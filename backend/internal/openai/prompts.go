@@ -7,7 +7,10 @@ import (
 )
 
 func modePrompt(mode string) string {
-	v := promptVarsForMode(mode)
+	return renderPrompt(promptVarsForMode(mode))
+}
+
+func renderPrompt(v promptVars) string {
 	tpl, err := template.New("homework_prompt").Parse(promptTemplate)
 	if err != nil {
 		return fallbackPrompt(v)
@@ -20,8 +23,26 @@ func modePrompt(mode string) string {
 }
 
 type promptVars struct {
-	ModeLabel string
-	ModeRule  string
+	ModeLabel  string
+	ModeRule   string
+	Focus      string
+	Grade      string
+	Edition    string
+	Region     string
+	Vocabulary string
+	// SubjectFields lists the subject-specific JSON fields, see subjectFields.
+	SubjectFields string
+	// Correction points out a failed arithmetic check when re-running an analysis.
+	Correction string
+	// Language is the output language rule, empty for simplified Chinese.
+	Language string
+	// QuestionText is the question of a text-only analysis; Spoken marks it as
+	// transcribed speech rather than typed.
+	QuestionText string
+	Spoken       bool
+	// Corrected is the parent's fix of the question on the photo; it overrides
+	// what the model reads from the image.
+	Corrected string
 }
 
 func promptVarsForMode(mode string) promptVars {
@@ -38,12 +59,48 @@ func promptVarsForMode(mode string) promptVars {
 	return m["guided"]
 }
 
+// PromptVersion names the analysis prompt revision stored with each result
+// version. Bump it whenever promptTemplate or the analysis schema changes.
+const PromptVersion = "2026-10-v1"
+
 const promptTemplate = `
 你是一名有耐心的小学家庭学习教练。
+{{- if .QuestionText}}
+{{- if .Spoken}}
+这道题没有图片，是家长或孩子口述后转写的文字（可包含数学、语文、英语等小学作业），可能有口语、重复或同音错字，请先理解题意：
+{{- else}}
+这道题没有图片，是家长输入的文字（可包含数学、语文、英语等小学作业），请先理解题意：
+{{- end}}
+{{.QuestionText}}
+{{- if .Spoken}}
+question_text 填写整理成书面形式的题干；听不懂题意时 legibility 填 unreadable，retake_tips 给出重新录音的建议（如安静环境、完整念出题目）。
+{{- else}}
+question_text 原样填写这段文字；看不懂题意时 legibility 填 unreadable，retake_tips 给出把题目补充完整的建议。
+{{- end}}
+{{- else}}
 请你先阅读图片中的题目（可包含数学、语文、英语等小学作业），直接做题意理解，不需要单独 OCR 步骤。
+{{- end}}
 输出目标：给家长“可立即照着说”的辅导内容，帮助孩子主动思考，提升体验而不是灌输答案。
 输出风格标签：{{.ModeLabel}}
 模式规则：{{.ModeRule}}
+{{- if .Focus}}
+图片中可能有多道题，只分析下面这一道，忽略其他题目：{{.Focus}}
+{{- end}}
+{{- if .Corrected}}
+家长已核对过这道题的文字，图片识别可能看错了数字或文字：以下面的文字为准理解题意，question_text 原样填写这段文字，图片只用来参考图形、排版等文字表达不了的信息：{{.Corrected}}
+{{- end}}
+{{- if .Grade}}
+孩子现在读{{.Grade}}，讲解方法和用词只用该年级已经学过的知识，suggested_grade 填写“{{.Grade}}”。
+{{- end}}
+{{- if .Edition}}
+孩子使用{{.Edition}}教材，解题方法和术语按该版本教材的讲法。
+{{- end}}
+{{- if .Region}}
+孩子在{{.Region}}上学，举例可以贴近当地生活。
+{{- end}}
+{{- if .Vocabulary}}
+knowledge_points 优先从下面这些学校使用的知识点名称中选择并原样使用，确实没有合适的才自拟：{{.Vocabulary}}
+{{- end}}
 严格使用以下 JSON 字段，不能增删字段，不能输出 markdown：
 - question_text: 题干原文，尽量完整，保持原题语义。
 - solution_thoughts: 给家长看的解题思路，先思路后步骤。
@@ -52,12 +109,130 @@ const promptTemplate = `
 - child_stuck_points: 恰好2条孩子可能卡点，要具体。
 - knowledge_points: 知识点列表，2-5条。
 - suggested_grade: 建议年级（如“三年级”）。
+- legibility: 图片中题目是否看得清：clear（清楚）、partial（部分模糊但能确定题意）、unreadable（看不清或没有题目）。
+- confidence: 0-1 的小数，表示你对题目识别和解答正确的把握。
+- retake_tips: 仅在 unreadable 时给出 1-3 条重新拍照的具体建议（如光线、角度、对焦），否则为空列表。
+看不清题目时不要猜测或编造题目，legibility 填 unreadable，question_text 填空字符串，其余字段简短填写即可。
+{{- if .SubjectFields}}
+{{.SubjectFields}}
+{{- end}}
+{{- if .Correction}}
+{{.Correction}}
+{{- end}}
+{{- if .Language}}
+{{.Language}}
+{{- end}}
 质量要求：
 1) 家长引导话术必须具体、可执行，避免空话。
 2) 语言积极，不责备孩子。
 3) quick 模式保持简洁；detailed 模式覆盖完整步骤；noanswer 模式禁止给出最终答案。
 4) 不能输出 markdown，不能输出 JSON 之外的任何内容。`
 
+// followUpSystemPrompt frames the follow-up chat. It reuses the mode rule of the
+// original analysis so e.g. a noanswer record never reveals the answer later on.
+func followUpSystemPrompt(mode, lang string) string {
+	v := promptVarsForMode(mode)
+	return "你是一名有耐心的小学家庭学习教练，正在和家长继续讨论一道已经分析过的作业题。" +
+		"家长会追问孩子可能的回答或卡点，请结合题目、图片和之前的分析，给出家长可以直接照着说的建议。" +
+		"回答用简短口语化的" + replyLanguage(lang) + "纯文本，不要输出 markdown 或 JSON。" +
+		"输出风格标签：" + v.ModeLabel + "。模式规则：" + v.ModeRule +
+		"即使家长在追问中要求，也必须遵守该模式规则。"
+}
+
+// childRolePrompt makes the model play a primary-school child who is stuck on the
+// question, drawing confusions from the analysis' stuck points.
+func childRolePrompt(in RehearsalInput) string {
+	grade := in.Result.SuggestedGrade
+	if grade == "" {
+		grade = "小学"
+	}
+	return "你在扮演一名" + grade + "的孩子，家长正在练习如何辅导你做下面这道题。\n" +
+		"题目：" + in.QuestionText + "\n" +
+		"你可能的卡点：" + strings.Join(in.Result.ChildStuckPoints, "；") + "\n" +
+		"规则：\n" +
+		"1) 只用孩子的口吻说一到两句话，不要输出 markdown 或解释。\n" +
+		"2) 围绕上面的卡点表现出真实的困惑，可以算错、答非所问或者不耐烦。\n" +
+		"3) 家长的提问好时，逐步想明白一点；家长直接说出答案时，就顺着答案敷衍过去，不再思考。\n" +
+		"4) 不要主动说出正确答案。\n" +
+		"5) 用" + replyLanguage(in.Language) + "回答。"
+}
+
+const rehearsalScorePrompt = `
+下面是家长和孩子（由 AI 扮演）围绕一道小学作业题的辅导练习对话，请评价家长的引导。
+题目：%s
+参考解题思路：%s
+对话记录：
+%s
+严格输出 JSON，不能输出 markdown：
+- gave_away_answer: 家长是否直接说出了最终答案或关键结果。
+- score: 0-100 的整数，家长越能通过提问引导孩子自己思考、语气越积极分数越高；直接给出答案不应超过 60 分。
+- summary: 一句话总评。
+- strengths: 1-3 条做得好的地方。
+- suggestions: 1-3 条具体的改进建议，可以给出更好的问法。`
+
+const practicePrompt = `
+请根据下面这道已经分析过的小学作业题，出几道同类变式练习题，帮助家长确认孩子真正掌握了。
+原题：%s
+知识点：%s
+建议年级：%s
+要求：
+1) 恰好出 %d 道题，难度与原题相同，考查相同知识点，换数字或情境，不要和原题完全一样。
+2) 每道题必须有唯一、明确、简短的最终答案。
+严格输出 JSON，不能输出 markdown：
+- problems: 题目列表，每项包含：
+  - question: 题干。
+  - answer: 最终答案，只写结果（如“360”或“360个”），不写过程。
+  - hint: 一句不透露答案的提示。`
+
+const repairPrompt = `
+下面是一份作业分析 JSON，其中部分字段不符合要求：
+%s
+问题：
+%s
+请结合图片只重新填写这些字段：%s。其他字段的内容保持不变，作为参考。字段要求与原来相同：列表条数必须符合要求，文字不能为空。严格输出 JSON，不能输出 markdown。`
+
+const answerOnlyPrompt = `
+只给出下面这道小学作业题的最终答案，只写结果（如“360”或“360个”），不写过程，严格输出 JSON。
+题目：%s`
+
+const subjectPrompt = `
+判断图片中这道小学作业题属于哪个学科，严格输出 JSON，不能输出 markdown：
+- subject: math（数学）、chinese（语文）、english（英语）或 other（其他学科，如科学、道德与法治）。`
+
+// subjectFields describes the subject-specific block appended to the analysis
+// fields; the keys match the blocks in subjectBlocks.
+var subjectFields = map[string]string{
+	SubjectMath: `- math: 数学题专用：
+  - steps: 1-8 步解题步骤，每步一句话。
+  - final_answer: 最终答案，只写结果（如“360”或“360个”）；noanswer 模式也要填写，服务端不会展示给家长。
+  - expression: 能直接算出最终答案的纯算式，只用数字、+ - * / 和括号（如“24*15”或“(48-6)/7”）；不是纯计算题时填空字符串。`,
+	SubjectChinese: `- chinese: 语文题专用：
+  - passage_summary: 阅读材料的内容概括，没有阅读材料时填空字符串。
+  - key_sentences: 0-5 条与答题相关的关键句，摘自原文。`,
+	SubjectEnglish: `- english: 英语题专用：
+  - vocabulary: 0-8 个需要掌握的单词或短语，每项包含 word 和中文 meaning。
+  - grammar_point: 本题考查的语法点，没有时填空字符串。`,
+}
+
+func weeklyReportSystemPrompt(lang string) string {
+	return "你是一名有耐心的小学家庭学习教练，帮家长回顾孩子一周的作业情况。" +
+		"只根据给出的统计数据说话，不要编造数据里没有的内容。回答用" + replyLanguage(lang) + "纯文本，不要输出 markdown 或 JSON。"
+}
+
+const weeklyReportPrompt = `
+下面是%s本周的作业统计（JSON）：daily 为周一到周日每天的题数，bySubject 为各学科题数，knowledgePoints 与 stuckPoints 为出现次数最多的知识点和卡点，recurringKnowledgePoints 为反复出现的知识点，previousTotal 为上周题数，newMistakes 为本周加入错题本的题数。
+%s
+请写一段 150 字以内的周报：先肯定孩子的投入，再指出反复出现的知识点或卡点，最后给家长下周一条具体可做的建议。语气积极，不责备孩子。`
+
 func fallbackPrompt(v promptVars) string {
 	return "你是一名有耐心的小学家庭学习教练。\n输出风格标签：" + v.ModeLabel + "\n模式规则：" + v.ModeRule
 }
+
+const pagePrompt = `
+你是一名有耐心的小学家庭学习教练。
+图片是一整页作业，请找出页面上每一道独立的题目，按从上到下、从左到右的阅读顺序列出。
+严格使用以下 JSON 字段，不能输出 markdown：
+- questions: 题目列表，每项包含：
+  - question_text: 该题题干原文，尽量完整。
+  - bbox: 该题在图片中的位置，x、y 为左上角坐标，width、height 为宽高，均为相对图片宽高的 0-1 小数。
+只列出题目，不要解题。大题下的小题各自单独列出。`
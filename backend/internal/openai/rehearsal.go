@@ -0,0 +1,114 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	oosdk "github.com/openai/openai-go"
+)
+
+// RehearsalInput is the context for a practice dialogue where the model plays the
+// child. Turns use the roles "parent" and "child".
+type RehearsalInput struct {
+	QuestionText string
+	Result       AnalyzeResult
+	Turns        []ChatTurn
+	Message      string
+	// Language is the output language of the child's lines and the score.
+	Language string
+}
+
+// RehearsalScore is the feedback given to the parent when a rehearsal ends.
+type RehearsalScore struct {
+	GaveAwayAnswer bool     `json:"gave_away_answer"`
+	Score          int      `json:"score"`
+	Summary        string   `json:"summary"`
+	Strengths      []string `json:"strengths"`
+	Suggestions    []string `json:"suggestions"`
+}
+
+// ChildReply returns the simulated child's next line. With an empty Message it
+// returns the child's opening reaction to the question.
+func (c *Client) ChildReply(ctx context.Context, in RehearsalInput) (string, error) {
+	messages := []oosdk.ChatCompletionMessageParamUnion{
+		oosdk.SystemMessage(childRolePrompt(in)),
+	}
+	for _, t := range in.Turns {
+		if t.Role == "child" {
+			messages = append(messages, oosdk.AssistantMessage(t.Content))
+		} else {
+			messages = append(messages, oosdk.UserMessage(t.Content))
+		}
+	}
+	msg := strings.TrimSpace(in.Message)
+	if msg == "" {
+		msg = "（家长把题目递给你，请说出你看到题目时的第一反应）"
+	}
+	messages = append(messages, oosdk.UserMessage(msg))
+
+	return c.completeText(ctx, "rehearsal", messages, 0.8)
+}
+
+// ScoreRehearsal judges the finished dialogue, mainly whether the parent guided the
+// child without giving the answer away.
+func (c *Client) ScoreRehearsal(ctx context.Context, in RehearsalInput) (RehearsalScore, error) {
+	var transcript strings.Builder
+	for _, t := range in.Turns {
+		speaker := "家长"
+		if t.Role == "child" {
+			speaker = "孩子"
+		}
+		fmt.Fprintf(&transcript, "%s：%s\n", speaker, t.Content)
+	}
+	prompt := fmt.Sprintf(strings.TrimSpace(rehearsalScorePrompt), in.QuestionText, in.Result.SolutionThoughts, transcript.String())
+
+	content, err := c.completeJSON(ctx, "rehearsal_score", in.Language, prompt, nil, "", jsonSchema{
+		Name:        "rehearsal_score",
+		Description: "Feedback on a parent's guidance during a rehearsal dialogue",
+		Schema:      rehearsalScoreSchema(),
+	})
+	if err != nil {
+		return RehearsalScore{}, err
+	}
+	return parseRehearsalScore(content)
+}
+
+// parseRehearsalScore decodes the model's score and keeps it within 0-100; giving
+// the answer away caps it at 60 whatever the model said.
+func parseRehearsalScore(content string) (RehearsalScore, error) {
+	var out RehearsalScore
+	if err := json.Unmarshal([]byte(content), &out); err != nil {
+		return RehearsalScore{}, fmt.Errorf("invalid completion json: %w", err)
+	}
+	if out.Score < 0 {
+		out.Score = 0
+	}
+	if out.Score > 100 {
+		out.Score = 100
+	}
+	if out.GaveAwayAnswer && out.Score > 60 {
+		out.Score = 60
+	}
+	return out, nil
+}
+
+func rehearsalScoreSchema() map[string]any {
+	strList := map[string]any{
+		"type": "array", "minItems": 1, "maxItems": 3,
+		"items": map[string]any{"type": "string"},
+	}
+	return map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"gave_away_answer", "score", "summary", "strengths", "suggestions"},
+		"properties": map[string]any{
+			"gave_away_answer": map[string]any{"type": "boolean"},
+			"score":            map[string]any{"type": "integer"},
+			"summary":          map[string]any{"type": "string"},
+			"strengths":        strList,
+			"suggestions":      strList,
+		},
+	}
+}
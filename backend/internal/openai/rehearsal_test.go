@@ -0,0 +1,53 @@
+package openai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRehearsalScore(t *testing.T) {
+	cases := []struct {
+		content string
+		want    int
+	}{
+		{`{"gave_away_answer":false,"score":85,"summary":"引导得好","strengths":["先问思路"],"suggestions":["多等一等"]}`, 85},
+		{`{"gave_away_answer":false,"score":130,"summary":"s","strengths":["a"],"suggestions":["b"]}`, 100},
+		{`{"gave_away_answer":false,"score":-5,"summary":"s","strengths":["a"],"suggestions":["b"]}`, 0},
+		{`{"gave_away_answer":true,"score":90,"summary":"s","strengths":["a"],"suggestions":["b"]}`, 60},
+		{`{"gave_away_answer":true,"score":40,"summary":"s","strengths":["a"],"suggestions":["b"]}`, 40},
+	}
+	for _, tc := range cases {
+		got, err := parseRehearsalScore(tc.content)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.content, err)
+		}
+		if got.Score != tc.want {
+			t.Fatalf("%s: score = %d, want %d", tc.content, got.Score, tc.want)
+		}
+	}
+	got, _ := parseRehearsalScore(`{"gave_away_answer":false,"score":85,"summary":"引导得好","strengths":["先问思路"],"suggestions":["多等一等"]}`)
+	if got.Summary != "引导得好" || len(got.Strengths) != 1 || got.Suggestions[0] != "多等一等" {
+		t.Fatalf("unexpected fields %+v", got)
+	}
+	if _, err := parseRehearsalScore("not json"); err == nil {
+		t.Fatalf("expected error for invalid json")
+	}
+}
+
+func TestChildRolePrompt(t *testing.T) {
+	in := RehearsalInput{
+		QuestionText: "24 × 15 = ?",
+		Result:       AnalyzeResult{SuggestedGrade: "三年级", ChildStuckPoints: []string{"只算了24×10", "忘记相加"}},
+	}
+	p := childRolePrompt(in)
+	for _, want := range []string{"三年级的孩子", "24 × 15 = ?", "只算了24×10；忘记相加", "不要主动说出正确答案", "用中文回答"} {
+		if !strings.Contains(p, want) {
+			t.Fatalf("child prompt misses %q: %q", want, p)
+		}
+	}
+	in.Result.SuggestedGrade = ""
+	in.Language = LanguageEN
+	if p := childRolePrompt(in); !strings.Contains(p, "小学的孩子") || !strings.Contains(p, "用英文回答") {
+		t.Fatalf("unexpected default grade or language: %q", p)
+	}
+}
@@ -0,0 +1,25 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	oosdk "github.com/openai/openai-go"
+)
+
+// WeeklyNarrative writes a short note to the parent about a week of homework, from
+// the week's aggregated statistics as JSON, in lang (see NormalizeLanguage).
+func (c *Client) WeeklyNarrative(ctx context.Context, childName, grade, statsJSON, lang string) (string, error) {
+	who := childName
+	if who == "" {
+		who = "孩子"
+	}
+	if grade != "" {
+		who += "（" + grade + "）"
+	}
+	messages := []oosdk.ChatCompletionMessageParamUnion{
+		oosdk.SystemMessage(weeklyReportSystemPrompt(lang)),
+		oosdk.UserMessage(fmt.Sprintf(weeklyReportPrompt, who, statsJSON)),
+	}
+	return c.completeText(ctx, "weekly_report", messages, 0.5)
+}
@@ -0,0 +1,134 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	oosdk "github.com/openai/openai-go"
+
+	"whatsdot-aibuddy/backend/internal/safety"
+)
+
+// SafetyChecker screens the texts of a result; see safety.Filter.
+type SafetyChecker interface {
+	Check(ctx context.Context, texts []safety.Text) []safety.Finding
+}
+
+// UnsafeError is returned by Analyze when the result still failed the safety
+// check after any re-run. Findings say where and why.
+type UnsafeError struct {
+	Findings    []safety.Finding
+	Regenerated bool
+}
+
+func (e *UnsafeError) Error() string {
+	categories := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		categories = append(categories, f.Field+":"+f.Category)
+	}
+	return "unsafe analysis result: " + strings.Join(categories, ", ")
+}
+
+// guardSafety screens a finished result. Unsafe results are re-run once when
+// SafetyRegenerate is set and blocked otherwise; the findings of a re-run that
+// passed are kept on the result for the incident log.
+func (c *Client) guardSafety(ctx context.Context, in AnalyzeInput, vars promptVars, subject string, out AnalyzeResult) (AnalyzeResult, error) {
+	findings := c.Safety.Check(ctx, resultTexts(out))
+	if len(findings) == 0 {
+		return out, nil
+	}
+	if !c.SafetyRegenerate {
+		return AnalyzeResult{}, &UnsafeError{Findings: findings}
+	}
+	log.Printf("[WARN] analysis failed safety check, regenerating: %v", (&UnsafeError{Findings: findings}).Error())
+
+	vars.Correction = strings.TrimSpace(vars.Correction + "\n上一次的输出包含不适合孩子的内容。所有字段必须温和、积极，不能出现暴力、色情或贬低孩子的词语。")
+	retry, err := c.analyzeOnce(ctx, in, vars, subject)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+	if retry.Unreadable() {
+		return retry, nil
+	}
+	retry.Verification, retry.LowConfidence = out.Verification, out.LowConfidence
+	if in.Mode == "noanswer" {
+		retry = c.guardAnswerLeak(ctx, in, vars, subject, retry)
+	}
+	if again := c.Safety.Check(ctx, resultTexts(retry)); len(again) > 0 {
+		return AnalyzeResult{}, &UnsafeError{Findings: again, Regenerated: true}
+	}
+	retry.SafetyFindings = findings
+	return retry, nil
+}
+
+// resultTexts lists the text fields of a result by JSON path. explain_to_child
+// and parent_guidance are what the child hears and are marked Aloud; the rest,
+// including the question and quoted sentences, is screened with the rules that
+// apply to every field.
+func resultTexts(out AnalyzeResult) []safety.Text {
+	var texts []safety.Text
+	add := func(field, value string, aloud bool) {
+		if strings.TrimSpace(value) != "" {
+			texts = append(texts, safety.Text{Field: field, Value: value, Aloud: aloud})
+		}
+	}
+	list := func(field string, values []string, aloud bool) {
+		for i, v := range values {
+			add(fmt.Sprintf("%s[%d]", field, i), v, aloud)
+		}
+	}
+	add("question_text", out.QuestionText, false)
+	add("solution_thoughts", out.SolutionThoughts, false)
+	add("explain_to_child", out.ExplainToChild, true)
+	list("parent_guidance", out.ParentGuidance, true)
+	list("child_stuck_points", out.ChildStuckPoints, false)
+	list("knowledge_points", out.KnowledgePoints, false)
+	if out.Math != nil {
+		list("math.steps", out.Math.Steps, false)
+	}
+	if out.Chinese != nil {
+		add("chinese.passage_summary", out.Chinese.PassageSummary, false)
+		list("chinese.key_sentences", out.Chinese.KeySentences, false)
+	}
+	if out.English != nil {
+		add("english.grammar_point", out.English.GrammarPoint, false)
+		for i, v := range out.English.Vocabulary {
+			add(fmt.Sprintf("english.vocabulary[%d]", i), v.Word+" "+v.Meaning, false)
+		}
+	}
+	return texts
+}
+
+// Moderate sends inputs to the moderation endpoint and returns the flagged
+// categories of each; it makes the client a safety.Moderator.
+func (c *Client) Moderate(ctx context.Context, inputs []string) ([][]string, error) {
+	if strings.TrimSpace(c.APIKey) == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY is empty")
+	}
+	resp, err := c.SDK.Moderations.New(ctx, oosdk.ModerationNewParams{
+		Input: oosdk.ModerationNewParamsInputUnion{OfStringArray: inputs},
+		Model: oosdk.ModerationModelOmniModerationLatest,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+	out := make([][]string, len(resp.Results))
+	for i, r := range resp.Results {
+		if !r.Flagged {
+			continue
+		}
+		var categories map[string]bool
+		if err := json.Unmarshal([]byte(r.Categories.RawJSON()), &categories); err != nil {
+			return nil, fmt.Errorf("invalid moderation categories: %w", err)
+		}
+		for name, flagged := range categories {
+			if flagged {
+				out[i] = append(out[i], name)
+			}
+		}
+	}
+	return out, nil
+}
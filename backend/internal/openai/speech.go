@@ -0,0 +1,80 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	oosdk "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// MaxSpeechInput is the longest text the speech endpoint accepts, in characters.
+const MaxSpeechInput = 4096
+
+// Speaker synthesizes speech through an OpenAI-compatible /audio/speech endpoint.
+// It has its own base URL so a local provider (or a test server) can stand in.
+type Speaker struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	// Voice is used when a request does not pick one.
+	Voice string
+	SDK   oosdk.Client
+}
+
+func NewSpeaker(baseURL, apiKey, model, voice string) *Speaker {
+	opts := []option.RequestOption{
+		option.WithAPIKey(apiKey),
+		option.WithBaseURL(strings.TrimRight(baseURL, "/")),
+		option.WithRequestTimeout(45 * time.Second),
+	}
+	return &Speaker{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		Voice:   voice,
+		SDK:     oosdk.NewClient(opts...),
+	}
+}
+
+// Speak returns the text read aloud as mp3. An empty voice uses the default one.
+func (s *Speaker) Speak(ctx context.Context, text, voice string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("empty speech input")
+	}
+	if utf8.RuneCountInString(text) > MaxSpeechInput {
+		return nil, fmt.Errorf("speech input longer than %d characters", MaxSpeechInput)
+	}
+	if voice == "" {
+		voice = s.Voice
+	}
+	log.Printf("[OPENAI_REQ] endpoint=%s domain=%s model=%s mode=speech voice=%s chars=%d",
+		s.BaseURL, extractDomain(s.BaseURL), s.Model, voice, utf8.RuneCountInString(text))
+	resp, err := s.SDK.Audio.Speech.New(ctx, oosdk.AudioSpeechNewParams{
+		Input:          text,
+		Model:          s.Model,
+		Voice:          oosdk.AudioSpeechNewParamsVoice(voice),
+		ResponseFormat: oosdk.AudioSpeechNewParamsResponseFormatMP3,
+	})
+	if err != nil {
+		log.Printf("[OPENAI_ERR] endpoint=%s domain=%s model=%s mode=speech err=%v",
+			s.BaseURL, extractDomain(s.BaseURL), s.Model, err)
+		return nil, fmt.Errorf("speech request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read speech audio: %w", err)
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty speech audio")
+	}
+	return b, nil
+}
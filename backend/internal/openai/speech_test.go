@@ -0,0 +1,76 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpeakUsesLocalProvider(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		_, _ = w.Write([]byte("ID3fake"))
+	}))
+	defer srv.Close()
+
+	sp := NewSpeaker(srv.URL+"/v1", "test", "tts-test", "nova")
+	b, err := sp.Speak(context.Background(), " 我们先算 24×10。 ", "")
+	if err != nil {
+		t.Fatalf("speak: %v", err)
+	}
+	if string(b) != "ID3fake" {
+		t.Fatalf("audio = %q", b)
+	}
+	if got["input"] != "我们先算 24×10。" || got["voice"] != "nova" || got["model"] != "tts-test" || got["response_format"] != "mp3" {
+		t.Fatalf("unexpected request %v", got)
+	}
+
+	if _, err := sp.Speak(context.Background(), "  ", ""); err == nil {
+		t.Fatalf("expected error for empty input")
+	}
+}
+
+func TestTranscribeUsesLocalProvider(t *testing.T) {
+	var model, filename string
+	spoken := " 二十四乘十五等于多少？ "
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		model = r.FormValue("model")
+		if _, fh, err := r.FormFile("file"); err == nil {
+			filename = fh.Filename
+		}
+		w.Header().Set("Content-Type", "application/json")
+		b, _ := json.Marshal(map[string]string{"text": spoken})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	tr := NewTranscriber(srv.URL+"/v1", "test", "stt-test")
+	text, err := tr.Transcribe(context.Background(), []byte("ID3fake"), "question.mp3", "audio/mpeg")
+	if err != nil {
+		t.Fatalf("transcribe: %v", err)
+	}
+	if text != "二十四乘十五等于多少？" || model != "stt-test" || filename != "question.mp3" {
+		t.Fatalf("text=%q model=%q filename=%q", text, model, filename)
+	}
+
+	spoken = "  "
+	if _, err := tr.Transcribe(context.Background(), []byte("ID3fake"), "question.mp3", "audio/mpeg"); !errors.Is(err, ErrEmptyTranscription) {
+		t.Fatalf("silence should be ErrEmptyTranscription, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Subjects an analysis can be classified as. Each subject but other adds its own
+// block to the common analysis fields.
+const (
+	SubjectMath    = "math"
+	SubjectChinese = "chinese"
+	SubjectEnglish = "english"
+	SubjectOther   = "other"
+)
+
+// MathDetails are the math-specific fields: worked steps and the final answer.
+type MathDetails struct {
+	Steps       []string `json:"steps"`
+	FinalAnswer string   `json:"final_answer"`
+	// Expression is a plain arithmetic expression whose value is the final answer,
+	// empty when the question is not pure calculation.
+	Expression string `json:"expression"`
+}
+
+// ChineseDetails cover reading questions: what the passage says and the sentences
+// that carry the answer.
+type ChineseDetails struct {
+	PassageSummary string   `json:"passage_summary"`
+	KeySentences   []string `json:"key_sentences"`
+}
+
+// EnglishDetails list the words to know and the grammar point being tested.
+type EnglishDetails struct {
+	Vocabulary   []VocabularyItem `json:"vocabulary"`
+	GrammarPoint string           `json:"grammar_point"`
+}
+
+type VocabularyItem struct {
+	Word    string `json:"word"`
+	Meaning string `json:"meaning"`
+}
+
+// NormalizeSubject maps a subject name, English or Chinese, to one of the Subject
+// constants, or "" when it is not recognized.
+func NormalizeSubject(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case SubjectMath, "数学":
+		return SubjectMath
+	case SubjectChinese, "语文":
+		return SubjectChinese
+	case SubjectEnglish, "英语":
+		return SubjectEnglish
+	case SubjectOther, "其他":
+		return SubjectOther
+	default:
+		return ""
+	}
+}
+
+// ClassifySubject decides which subject the (focused) question on the photo
+// belongs to, so the analysis can use that subject's schema. Without an image,
+// focus is the whole question as text. lang is the request's output language.
+func (c *Client) ClassifySubject(ctx context.Context, imageBytes []byte, contentType, focus, lang string) (string, error) {
+	prompt := strings.TrimSpace(subjectPrompt)
+	focus = strings.TrimSpace(focus)
+	switch {
+	case len(imageBytes) == 0:
+		prompt = strings.Replace(prompt, "图片中这道", "这道", 1) + "\n题目：" + focus
+	case focus != "":
+		prompt += "\n图片中可能有多道题，只判断这一道：" + focus
+	}
+	content, err := c.completeJSON(ctx, "subject", lang, prompt, imageBytes, contentType, jsonSchema{
+		Name:        "homework_subject",
+		Description: "Subject of a primary school homework question",
+		Schema:      subjectSchema(),
+	})
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Subject string `json:"subject"`
+	}
+	if err := json.Unmarshal([]byte(content), &out); err != nil {
+		return "", fmt.Errorf("invalid completion json: %w", err)
+	}
+	if s := NormalizeSubject(out.Subject); s != "" {
+		return s, nil
+	}
+	return SubjectOther, nil
+}
+
+func subjectSchema() map[string]any {
+	return map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"subject"},
+		"properties": map[string]any{
+			"subject": map[string]any{
+				"type": "string",
+				"enum": []string{SubjectMath, SubjectChinese, SubjectEnglish, SubjectOther},
+			},
+		},
+	}
+}
+
+// analysisSchemaFor extends the common analysis schema with the subject's block.
+func analysisSchemaFor(subject string) map[string]any {
+	schema := analysisSchema()
+	extra, ok := subjectBlocks()[subject]
+	if !ok {
+		return schema
+	}
+	props := schema["properties"].(map[string]any)
+	props[subject] = extra
+	schema["required"] = append(schema["required"].([]string), subject)
+	return schema
+}
+
+func subjectBlocks() map[string]map[string]any {
+	str := map[string]any{"type": "string"}
+	strList := func(min, max int) map[string]any {
+		return map[string]any{"type": "array", "minItems": min, "maxItems": max, "items": str}
+	}
+	object := func(props map[string]any) map[string]any {
+		required := make([]string, 0, len(props))
+		for k := range props {
+			required = append(required, k)
+		}
+		sort.Strings(required)
+		return map[string]any{"type": "object", "additionalProperties": false, "required": required, "properties": props}
+	}
+	return map[string]map[string]any{
+		SubjectMath: object(map[string]any{
+			"steps":        strList(1, 8),
+			"final_answer": str,
+			"expression":   str,
+		}),
+		SubjectChinese: object(map[string]any{
+			"passage_summary": str,
+			"key_sentences":   strList(0, 5),
+		}),
+		SubjectEnglish: object(map[string]any{
+			"vocabulary": map[string]any{
+				"type": "array", "minItems": 0, "maxItems": 8,
+				"items": object(map[string]any{"word": str, "meaning": str}),
+			},
+			"grammar_point": str,
+		}),
+	}
+}
+
+// normalizeSubjectDetails trims the subject block and drops blocks that do not
+// belong to the classified subject.
+func normalizeSubjectDetails(out AnalyzeResult) AnalyzeResult {
+	if out.Subject != SubjectMath {
+		out.Math = nil
+	} else if out.Math != nil {
+		out.Math.Steps = trimList(out.Math.Steps, 8)
+		out.Math.FinalAnswer = strings.TrimSpace(out.Math.FinalAnswer)
+		out.Math.Expression = strings.TrimSpace(out.Math.Expression)
+	}
+	if out.Subject != SubjectChinese {
+		out.Chinese = nil
+	} else if out.Chinese != nil {
+		out.Chinese.PassageSummary = strings.TrimSpace(out.Chinese.PassageSummary)
+		out.Chinese.KeySentences = trimList(out.Chinese.KeySentences, 5)
+	}
+	if out.Subject != SubjectEnglish {
+		out.English = nil
+	} else if out.English != nil {
+		out.English.GrammarPoint = strings.TrimSpace(out.English.GrammarPoint)
+		vocab := out.English.Vocabulary[:0]
+		for _, v := range out.English.Vocabulary {
+			v.Word, v.Meaning = strings.TrimSpace(v.Word), strings.TrimSpace(v.Meaning)
+			if v.Word != "" && len(vocab) < 8 {
+				vocab = append(vocab, v)
+			}
+		}
+		out.English.Vocabulary = vocab
+	}
+	return out
+}
+
+// HideAnswer removes the final answer, the last step that reaches it and the
+// verified value from noanswer results. The model still fills them so the
+// arithmetic can be verified.
+func HideAnswer(out AnalyzeResult, mode string) AnalyzeResult {
+	if mode != "noanswer" {
+		return out
+	}
+	if out.Math != nil {
+		m := *out.Math
+		m.FinalAnswer = ""
+		if n := len(m.Steps); n > 0 {
+			m.Steps = append([]string(nil), m.Steps[:n-1]...)
+		}
+		out.Math = &m
+	}
+	if out.Verification != nil {
+		v := *out.Verification
+		v.Computed = ""
+		out.Verification = &v
+	}
+	return out
+}
+
+func trimList(list []string, max int) []string {
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" && len(out) < max {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	oosdk "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// ErrEmptyTranscription is returned when nothing could be heard in a recording,
+// such as silence; it asks for a new recording rather than reporting a failure.
+var ErrEmptyTranscription = errors.New("empty transcription")
+
+// Transcriber turns recordings into text through an OpenAI-compatible
+// /audio/transcriptions endpoint, with its own base URL like Speaker.
+type Transcriber struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	SDK     oosdk.Client
+}
+
+func NewTranscriber(baseURL, apiKey, model string) *Transcriber {
+	opts := []option.RequestOption{
+		option.WithAPIKey(apiKey),
+		option.WithBaseURL(strings.TrimRight(baseURL, "/")),
+		option.WithRequestTimeout(60 * time.Second),
+	}
+	return &Transcriber{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		SDK:     oosdk.NewClient(opts...),
+	}
+}
+
+// Transcribe returns the text spoken in audio; filename carries the format
+// extension the endpoint expects.
+func (t *Transcriber) Transcribe(ctx context.Context, audio []byte, filename, contentType string) (string, error) {
+	log.Printf("[OPENAI_REQ] endpoint=%s domain=%s model=%s mode=transcription content_type=%s audio_bytes=%d",
+		t.BaseURL, extractDomain(t.BaseURL), t.Model, contentType, len(audio))
+	resp, err := t.SDK.Audio.Transcriptions.New(ctx, oosdk.AudioTranscriptionNewParams{
+		File:   oosdk.File(bytes.NewReader(audio), filename, contentType),
+		Model:  t.Model,
+		Prompt: oosdk.String("小学生或家长口述的一道作业题，可能中英文混合。"),
+	})
+	if err != nil {
+		log.Printf("[OPENAI_ERR] endpoint=%s domain=%s model=%s mode=transcription err=%v",
+			t.BaseURL, extractDomain(t.BaseURL), t.Model, err)
+		return "", fmt.Errorf("transcription request failed: %w", err)
+	}
+	text := strings.TrimSpace(resp.Text)
+	if text == "" {
+		return "", ErrEmptyTranscription
+	}
+	return text, nil
+}
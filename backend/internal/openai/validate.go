@@ -0,0 +1,296 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Violation is one place where model output breaks its schema. Path uses JSON
+// field names, e.g. "parent_guidance[1]" or "math.steps".
+type Violation struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError is returned when model output still breaks its schema after
+// the repair step.
+type ValidationError struct {
+	Schema     string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Path + ": " + v.Reason
+	}
+	return fmt.Sprintf("%s output violates schema: %s", e.Schema, strings.Join(parts, "; "))
+}
+
+// extractJSON returns the JSON object in content, tolerating markdown fences and
+// prose around it from providers that ignore strict mode.
+func extractJSON(content string) string {
+	s := strings.TrimSpace(content)
+	if strings.HasPrefix(s, "{") && json.Valid([]byte(s)) {
+		return s
+	}
+	if i := strings.Index(s, "```"); i >= 0 {
+		body := s[i+3:]
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			body = body[nl+1:]
+		}
+		if j := strings.Index(body, "```"); j >= 0 {
+			body = strings.TrimSpace(body[:j])
+			if json.Valid([]byte(body)) {
+				return body
+			}
+		}
+	}
+	start, end := strings.IndexByte(s, '{'), strings.LastIndexByte(s, '}')
+	if start >= 0 && end > start && json.Valid([]byte(s[start:end+1])) {
+		return s[start : end+1]
+	}
+	return s
+}
+
+// validator checks decoded JSON against the subset of JSON Schema the prompts use:
+// type, required, additionalProperties, enum, minItems and maxItems. Strings must
+// be non-empty unless their path is in allowEmpty.
+type validator struct {
+	allowEmpty map[string]bool
+	out        []Violation
+}
+
+func (v *validator) check(path string, value any, schema map[string]any) {
+	if value == nil {
+		v.add(path, "missing")
+		return
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			v.add(path, "want object")
+			return
+		}
+		props, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			sub, _ := props[name].(map[string]any)
+			v.check(joinPath(path, name), obj[name], sub)
+		}
+		if schema["additionalProperties"] == false {
+			extra := make([]string, 0)
+			for name := range obj {
+				if _, ok := props[name]; !ok {
+					extra = append(extra, name)
+				}
+			}
+			sort.Strings(extra)
+			for _, name := range extra {
+				v.add(joinPath(path, name), "unexpected field")
+			}
+		}
+	case "array":
+		list, ok := value.([]any)
+		if !ok {
+			v.add(path, "want array")
+			return
+		}
+		if min, ok := schema["minItems"].(int); ok && len(list) < min {
+			v.add(path, fmt.Sprintf("want at least %d items, got %d", min, len(list)))
+		}
+		if max, ok := schema["maxItems"].(int); ok && len(list) > max {
+			v.add(path, fmt.Sprintf("want at most %d items, got %d", max, len(list)))
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range list {
+			v.check(fmt.Sprintf("%s[%d]", path, i), item, items)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v.add(path, "want string")
+			return
+		}
+		if enum, ok := schema["enum"].([]string); ok && !containsString(enum, s) {
+			v.add(path, "want one of "+strings.Join(enum, ", "))
+			return
+		}
+		if strings.TrimSpace(s) == "" && !v.allowEmpty[trimIndex(path)] {
+			v.add(path, "empty")
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			v.add(path, "want number")
+		}
+	}
+}
+
+func (v *validator) add(path, reason string) {
+	v.out = append(v.out, Violation{Path: path, Reason: reason})
+}
+
+// analysisAllowEmpty are the analysis strings the prompt lets the model leave empty.
+var analysisAllowEmpty = map[string]bool{
+	"question_text":           true,
+	"retake_tips":             true,
+	"math.final_answer":       true,
+	"math.expression":         true,
+	"chinese.passage_summary": true,
+	"english.grammar_point":   true,
+}
+
+// validateAnalysis checks an analysis object. An unreadable photo only needs the
+// right shape: the prompt asks for short placeholder fields then.
+func validateAnalysis(obj map[string]any, schema map[string]any) []Violation {
+	v := &validator{allowEmpty: analysisAllowEmpty}
+	v.check("", obj, schema)
+	if obj["legibility"] != LegibilityUnreadable {
+		return v.out
+	}
+	shape := v.out[:0]
+	for _, x := range v.out {
+		if x.Reason != "empty" && !strings.HasPrefix(x.Reason, "want at least") {
+			shape = append(shape, x)
+		}
+	}
+	return shape
+}
+
+// decodeAnalysis extracts, validates and, if needed, repairs analysis output.
+// Unexpected fields are dropped locally; every other violation is sent back to the
+// model, which may only rewrite the violating top-level fields.
+func (c *Client) decodeAnalysis(ctx context.Context, in AnalyzeInput, subject, content string) (AnalyzeResult, error) {
+	schema := analysisSchemaFor(subject)
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(extractJSON(content)), &obj); err != nil {
+		return AnalyzeResult{}, fmt.Errorf("invalid completion json: %w", err)
+	}
+	violations := dropUnexpected(obj, validateAnalysis(obj, schema))
+	if len(violations) > 0 {
+		log.Printf("[WARN] analysis output violates schema, repairing: %v", (&ValidationError{Schema: "homework_analysis", Violations: violations}).Error())
+		repaired, err := c.repairFields(ctx, in, schema, obj, violations)
+		if err != nil {
+			return AnalyzeResult{}, err
+		}
+		obj = repaired
+		violations = dropUnexpected(obj, validateAnalysis(obj, schema))
+		if len(violations) > 0 {
+			return AnalyzeResult{}, &ValidationError{Schema: "homework_analysis", Violations: violations}
+		}
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return AnalyzeResult{}, err
+	}
+	var out AnalyzeResult
+	if err := json.Unmarshal(b, &out); err != nil {
+		return AnalyzeResult{}, fmt.Errorf("invalid completion json: %w", err)
+	}
+	return out, nil
+}
+
+// dropUnexpected deletes top-level fields reported as unexpected and returns the
+// remaining violations. Nested extras stay violations.
+func dropUnexpected(obj map[string]any, violations []Violation) []Violation {
+	rest := violations[:0]
+	for _, v := range violations {
+		if v.Reason == "unexpected field" && !strings.ContainsAny(v.Path, ".[") {
+			delete(obj, v.Path)
+			continue
+		}
+		rest = append(rest, v)
+	}
+	return rest
+}
+
+// repairFields asks the model to rewrite only the violating top-level fields of
+// obj and merges them back.
+func (c *Client) repairFields(ctx context.Context, in AnalyzeInput, schema, obj map[string]any, violations []Violation) (map[string]any, error) {
+	fields := violatingFields(violations)
+	props, _ := schema["properties"].(map[string]any)
+	sub := map[string]any{}
+	for _, f := range fields {
+		sub[f] = props[f]
+	}
+	current, _ := json.Marshal(obj)
+	problems := make([]string, len(violations))
+	for i, v := range violations {
+		problems[i] = "- " + v.Path + ": " + v.Reason
+	}
+	prompt := fmt.Sprintf(strings.TrimSpace(repairPrompt), current, strings.Join(problems, "\n"), strings.Join(fields, ", "))
+	content, err := c.completeJSON(ctx, "repair", in.Language, prompt, in.Image, in.ContentType, jsonSchema{
+		Name:        "homework_analysis_repair",
+		Description: "Corrected fields of a homework analysis",
+		Schema: map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             fields,
+			"properties":           sub,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var fixed map[string]any
+	if err := json.Unmarshal([]byte(extractJSON(content)), &fixed); err != nil {
+		return nil, fmt.Errorf("invalid repair json: %w", err)
+	}
+	for _, f := range fields {
+		if v, ok := fixed[f]; ok {
+			obj[f] = v
+		}
+	}
+	return obj, nil
+}
+
+// violatingFields lists the top-level fields the violations fall under, in order.
+func violatingFields(violations []Violation) []string {
+	var fields []string
+	for _, v := range violations {
+		f := v.Path
+		if i := strings.IndexAny(f, ".["); i >= 0 {
+			f = f[:i]
+		}
+		if !containsString(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func joinPath(base, name string) string {
+	if base == "" {
+		return name
+	}
+	return base + "." + name
+}
+
+// trimIndex drops list indexes so "retake_tips[0]" matches "retake_tips".
+func trimIndex(path string) string {
+	for {
+		i := strings.IndexByte(path, '[')
+		if i < 0 {
+			return path
+		}
+		j := strings.IndexByte(path[i:], ']')
+		if j < 0 {
+			return path
+		}
+		path = path[:i] + path[i+j+1:]
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
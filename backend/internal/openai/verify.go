@@ -0,0 +1,59 @@
+package openai
+
+import (
+	"strings"
+
+	"whatsdot-aibuddy/backend/internal/answer"
+	"whatsdot-aibuddy/backend/internal/expr"
+)
+
+// Verification statuses of a math result's arithmetic.
+const (
+	VerifyPassed    = "verified"
+	VerifyMismatch  = "mismatch"
+	VerifyUnchecked = "unchecked"
+)
+
+// Verification is the server's check of a math result: the model's expression is
+// evaluated and compared with its final answer.
+type Verification struct {
+	Status     string `json:"status"`
+	Expression string `json:"expression,omitempty"`
+	// Computed is the evaluated expression; it is only exposed on mismatches so the
+	// check does not leak the answer in noanswer mode.
+	Computed string `json:"computed,omitempty"`
+	Retried  bool   `json:"retried,omitempty"`
+}
+
+// verifyMath evaluates the math block's expression and compares it with the final
+// answer. Non-arithmetic questions (no expression) and division with a remainder
+// ("7余6", which a single number cannot express) are left unchecked.
+func verifyMath(out AnalyzeResult) Verification {
+	if out.Math == nil || strings.TrimSpace(out.Math.Expression) == "" {
+		return Verification{Status: VerifyUnchecked}
+	}
+	v := Verification{Status: VerifyUnchecked, Expression: out.Math.Expression}
+	final := answer.Normalize(out.Math.FinalAnswer)
+	if strings.ContainsAny(final, "余…") || strings.Contains(final, "...") {
+		return v
+	}
+	computed, err := expr.Eval(out.Math.Expression)
+	if err != nil {
+		return v
+	}
+	given, _, ok := answer.ParseNumber(final)
+	if !ok {
+		return v
+	}
+	if computed.Cmp(given) == 0 {
+		v.Status = VerifyPassed
+		return v
+	}
+	v.Status = VerifyMismatch
+	v.Computed = formatRat(computed.RatString())
+	return v
+}
+
+func formatRat(s string) string {
+	return strings.TrimSuffix(s, "/1")
+}
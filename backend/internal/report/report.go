@@ -0,0 +1,227 @@
+// Package report builds weekly learning summaries from a family's homework records.
+package report
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// Store is the subset of store.Store the generator needs.
+type Store interface {
+	ListReportRecords(ctx context.Context, deviceID string, childID int64, from, to time.Time) ([]store.ReportRecord, error)
+	CountReportRecords(ctx context.Context, deviceID string, childID int64, from, to time.Time) (int, error)
+	CountMistakesCreated(ctx context.Context, deviceID string, childID int64, from, to time.Time) (int, error)
+	GetWeeklyReport(ctx context.Context, deviceID string, childID int64, weekStart time.Time) (store.WeeklyReport, error)
+	UpsertWeeklyReport(ctx context.Context, deviceID string, childID int64, weekStart time.Time, stats any, narrative string) (store.WeeklyReport, error)
+}
+
+// Target is whose week is summarized: a child of the device, or the whole device
+// when ChildID is 0. Language is what the narrative is written in.
+type Target struct {
+	DeviceID  string
+	ChildID   int64
+	ChildName string
+	Grade     string
+	Language  string
+}
+
+// Count is a name with how many records of the week mention it.
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Stats are the aggregated numbers of one week.
+type Stats struct {
+	WeekStart  string         `json:"weekStart"`
+	Total      int            `json:"total"`
+	ActiveDays int            `json:"activeDays"`
+	Daily      []int          `json:"daily"`
+	BySubject  map[string]int `json:"bySubject"`
+	// KnowledgePoints and StuckPoints list the most frequent names; Recurring holds
+	// the knowledge points that came up in more than one record.
+	KnowledgePoints []Count    `json:"knowledgePoints"`
+	Recurring       []string   `json:"recurringKnowledgePoints"`
+	StuckPoints     []Count    `json:"stuckPoints"`
+	NewMistakes     int        `json:"newMistakes"`
+	PreviousTotal   int        `json:"previousTotal"`
+	Change          int        `json:"change"`
+	LastSolvedAt    *time.Time `json:"lastSolvedAt,omitempty"`
+	// ContentHash digests every subject, knowledge point and stuck point count, so
+	// a regenerated or edited record changes it even when the totals do not.
+	ContentHash string `json:"contentHash"`
+	// Language is the narrative's language; switching it rebuilds the report.
+	Language string `json:"language"`
+}
+
+const topN = 8
+
+// WeekStart returns the Monday 00:00 of the week containing t, in t's location.
+func WeekStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// Aggregate summarizes the records of the week starting at weekStart.
+func Aggregate(weekStart time.Time, records []store.ReportRecord) Stats {
+	st := Stats{
+		WeekStart: weekStart.Format("2006-01-02"),
+		Daily:     make([]int, 7),
+		BySubject: map[string]int{},
+	}
+	knowledge := map[string]int{}
+	stuck := map[string]int{}
+	for _, r := range records {
+		day := int(r.SolvedAt.In(weekStart.Location()).Sub(weekStart).Hours() / 24)
+		if day < 0 || day > 6 {
+			continue
+		}
+		st.Total++
+		st.Daily[day]++
+		subject := r.Subject
+		if subject == "" {
+			subject = "other"
+		}
+		st.BySubject[subject]++
+		if st.LastSolvedAt == nil || r.SolvedAt.After(*st.LastSolvedAt) {
+			at := r.SolvedAt
+			st.LastSolvedAt = &at
+		}
+
+		var result struct {
+			KnowledgePoints  []string `json:"knowledge_points"`
+			ChildStuckPoints []string `json:"child_stuck_points"`
+		}
+		_ = json.Unmarshal(r.Result, &result)
+		for _, k := range uniq(result.KnowledgePoints) {
+			knowledge[k]++
+		}
+		for _, k := range uniq(result.ChildStuckPoints) {
+			stuck[k]++
+		}
+	}
+	for _, n := range st.Daily {
+		if n > 0 {
+			st.ActiveDays++
+		}
+	}
+	st.KnowledgePoints = top(knowledge, topN)
+	for _, c := range st.KnowledgePoints {
+		if c.Count > 1 {
+			st.Recurring = append(st.Recurring, c.Name)
+		}
+	}
+	st.StuckPoints = top(stuck, 5)
+	st.ContentHash = contentHash(st.BySubject, knowledge, stuck)
+	return st
+}
+
+func contentHash(groups ...map[string]int) string {
+	h := sha256.New()
+	for _, counts := range groups {
+		for _, c := range top(counts, len(counts)) {
+			fmt.Fprintf(h, "%s\x00%d\x00", c.Name, c.Count)
+		}
+		h.Write([]byte{0xff})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Generator aggregates a week, asks Narrate for a short narrative when set, and
+// stores the report. A stored report whose numbers are unchanged is reused so the
+// narrative is only generated once per change.
+type Generator struct {
+	Store   Store
+	Narrate func(ctx context.Context, t Target, st Stats) (string, error)
+}
+
+func (g *Generator) Generate(ctx context.Context, t Target, weekStart time.Time) (store.WeeklyReport, error) {
+	weekStart = WeekStart(weekStart)
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	records, err := g.Store.ListReportRecords(ctx, t.DeviceID, t.ChildID, weekStart, weekEnd)
+	if err != nil {
+		return store.WeeklyReport{}, err
+	}
+	st := Aggregate(weekStart, records)
+	if st.PreviousTotal, err = g.Store.CountReportRecords(ctx, t.DeviceID, t.ChildID, weekStart.AddDate(0, 0, -7), weekStart); err != nil {
+		return store.WeeklyReport{}, err
+	}
+	st.Change = st.Total - st.PreviousTotal
+	st.Language = t.Language
+	if st.NewMistakes, err = g.Store.CountMistakesCreated(ctx, t.DeviceID, t.ChildID, weekStart, weekEnd); err != nil {
+		return store.WeeklyReport{}, err
+	}
+
+	stored, err := g.Store.GetWeeklyReport(ctx, t.DeviceID, t.ChildID, weekStart)
+	if err == nil && g.unchanged(stored, st) {
+		return stored, nil
+	}
+	if err != nil && !store.IsNotFound(err) {
+		return store.WeeklyReport{}, err
+	}
+
+	narrative := ""
+	if g.Narrate != nil && st.Total > 0 {
+		if narrative, err = g.Narrate(ctx, t, st); err != nil {
+			log.Printf("[WARN] weekly report narrative: %v", err)
+			narrative = ""
+		}
+	}
+	return g.Store.UpsertWeeklyReport(ctx, t.DeviceID, t.ChildID, weekStart, st, strings.TrimSpace(narrative))
+}
+
+func (g *Generator) unchanged(stored store.WeeklyReport, fresh Stats) bool {
+	var old Stats
+	if err := json.Unmarshal(stored.Stats, &old); err != nil {
+		return false
+	}
+	if g.Narrate != nil && fresh.Total > 0 && stored.Narrative == "" {
+		return false
+	}
+	sameLast := (old.LastSolvedAt == nil) == (fresh.LastSolvedAt == nil) &&
+		(old.LastSolvedAt == nil || old.LastSolvedAt.Equal(*fresh.LastSolvedAt))
+	return sameLast && old.Total == fresh.Total && old.NewMistakes == fresh.NewMistakes && old.PreviousTotal == fresh.PreviousTotal &&
+		old.ContentHash == fresh.ContentHash && old.Language == fresh.Language
+}
+
+func uniq(list []string) []string {
+	seen := map[string]bool{}
+	out := list[:0:0]
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func top(counts map[string]int, n int) []Count {
+	out := make([]Count, 0, len(counts))
+	for name, c := range counts {
+		out = append(out, Count{Name: name, Count: c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+func TestWeekStartIsMonday(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	cases := map[string]string{
+		"2026-10-19T09:00:00": "2026-10-19", // Monday
+		"2026-10-25T23:59:00": "2026-10-19", // Sunday
+		"2026-10-26T00:00:00": "2026-10-26",
+	}
+	for in, want := range cases {
+		at, _ := time.ParseInLocation("2006-01-02T15:04:05", in, loc)
+		if got := WeekStart(at).Format("2006-01-02"); got != want {
+			t.Fatalf("WeekStart(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestAggregateCountsSubjectsDaysAndRecurringPoints(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	week := time.Date(2026, 10, 19, 0, 0, 0, 0, loc)
+	result := func(points, stuck []string) json.RawMessage {
+		b, _ := json.Marshal(map[string]any{"knowledge_points": points, "child_stuck_points": stuck})
+		return b
+	}
+	records := []store.ReportRecord{
+		{Subject: "math", SolvedAt: week.Add(10 * time.Hour), Result: result([]string{"乘法分配律", "口算", "乘法分配律"}, []string{"忘记相加"})},
+		{Subject: "math", SolvedAt: week.Add(34 * time.Hour), Result: result([]string{"乘法分配律"}, []string{"忘记相加"})},
+		{Subject: "", SolvedAt: week.Add(35 * time.Hour), Result: json.RawMessage(`{}`)},
+		{Subject: "chinese", SolvedAt: week.AddDate(0, 0, 7), Result: result([]string{"比喻句"}, nil)},
+	}
+	st := Aggregate(week, records)
+	if st.Total != 3 || st.ActiveDays != 2 || !reflect.DeepEqual(st.Daily, []int{1, 2, 0, 0, 0, 0, 0}) {
+		t.Fatalf("unexpected totals: %+v", st)
+	}
+	if !reflect.DeepEqual(st.BySubject, map[string]int{"math": 2, "other": 1}) {
+		t.Fatalf("unexpected subjects: %v", st.BySubject)
+	}
+	if st.KnowledgePoints[0] != (Count{Name: "乘法分配律", Count: 2}) || !reflect.DeepEqual(st.Recurring, []string{"乘法分配律"}) {
+		t.Fatalf("unexpected knowledge points: %v %v", st.KnowledgePoints, st.Recurring)
+	}
+	if st.StuckPoints[0] != (Count{Name: "忘记相加", Count: 2}) {
+		t.Fatalf("unexpected stuck points: %v", st.StuckPoints)
+	}
+}
+
+func TestUnchangedNoticesRegeneratedResults(t *testing.T) {
+	week := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	records := []store.ReportRecord{
+		{Subject: "math", SolvedAt: week.Add(10 * time.Hour), Result: json.RawMessage(`{"knowledge_points":["口算"]}`)},
+	}
+	st := Aggregate(week, records)
+	raw, _ := json.Marshal(st)
+	stored := store.WeeklyReport{Stats: raw, Narrative: "本周练了口算。"}
+	g := &Generator{}
+
+	if !g.unchanged(stored, Aggregate(week, records)) {
+		t.Fatalf("same records should reuse the stored report")
+	}
+	// The record was regenerated in place: same count and solve time, new content.
+	records[0].Result = json.RawMessage(`{"knowledge_points":["乘法分配律"]}`)
+	if g.unchanged(stored, Aggregate(week, records)) {
+		t.Fatalf("changed knowledge points should rebuild the report")
+	}
+	records[0].Subject = "chinese"
+	records[0].Result = json.RawMessage(`{"knowledge_points":["口算"]}`)
+	if g.unchanged(stored, Aggregate(week, records)) {
+		t.Fatalf("changed subject should rebuild the report")
+	}
+	records[0].Subject = "math"
+	fresh := Aggregate(week, records)
+	fresh.Language = "en"
+	if g.unchanged(stored, fresh) {
+		t.Fatalf("changed language should rebuild the report")
+	}
+}
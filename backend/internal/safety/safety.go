@@ -0,0 +1,201 @@
+// Package safety screens generated text before it reaches a child: a local rule
+// set (word list and patterns) and an optional moderation provider.
+package safety
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Categories of unsafe content. Moderation findings keep the provider's own
+// category names.
+const (
+	CategoryViolence = "violence"
+	CategoryAdult    = "adult"
+	CategoryInsult   = "insult"
+)
+
+// Sources of a finding.
+const (
+	SourceRules      = "rules"
+	SourceModeration = "moderation"
+)
+
+//go:embed data/rules.txt
+var defaultRules embed.FS
+
+// Rule is one word-list term or pattern. An AloudOnly rule checks only text read
+// to the child.
+type Rule struct {
+	Category  string
+	Term      string
+	AloudOnly bool
+	re        *regexp.Regexp
+}
+
+// Text is one field of a result; Field names it in findings, e.g. "parent_guidance[1]".
+// Aloud marks text spoken to the child or parent, as opposed to the homework and
+// its retelling, where stories legitimately mention fighting or a fool.
+type Text struct {
+	Field string
+	Value string
+	Aloud bool
+}
+
+// Finding is one piece of unsafe content.
+type Finding struct {
+	Field    string `json:"field"`
+	Category string `json:"category"`
+	Source   string `json:"source"`
+	Term     string `json:"term,omitempty"`
+}
+
+// Moderator is a remote moderation provider. It returns, for each input, the
+// categories it flagged (empty when fine).
+type Moderator interface {
+	Moderate(ctx context.Context, inputs []string) ([][]string, error)
+}
+
+// Filter checks texts against Rules and, when set, Moderator.
+type Filter struct {
+	Rules     []Rule
+	Moderator Moderator
+}
+
+// Default returns the rules shipped with the server.
+func Default() ([]Rule, error) {
+	f, err := defaultRules.Open("data/rules.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRules(f)
+}
+
+// Load reads rules from a file in fsys, in the format of data/rules.txt.
+func Load(fsys fs.FS, name string) ([]Rule, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRules(f)
+}
+
+// ParseRules reads "<category> <term>" lines; blank lines and # comments are
+// skipped and "re:" terms are compiled as regular expressions. A "scope aloud"
+// line makes the rules after it AloudOnly until a "scope all" line.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	aloud := false
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		category, term, ok := strings.Cut(line, " ")
+		term = strings.TrimSpace(term)
+		if !ok || term == "" {
+			return nil, fmt.Errorf("line %d: want <category> <term>", n)
+		}
+		if category == "scope" {
+			switch term {
+			case "aloud", "all":
+				aloud = term == "aloud"
+				continue
+			}
+			return nil, fmt.Errorf("line %d: want scope aloud or scope all", n)
+		}
+		rule := Rule{Category: category, Term: term, AloudOnly: aloud}
+		if pattern, isRe := strings.CutPrefix(term, "re:"); isRe {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			rule.re = re
+		} else {
+			rule.Term = compact(term)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, sc.Err()
+}
+
+// Check returns every finding in texts. Rules always run; a moderation error is
+// logged and the rule findings stand on their own. Text not read aloud is held to
+// the adult rules and the provider's sexual categories only.
+func (f *Filter) Check(ctx context.Context, texts []Text) []Finding {
+	var findings []Finding
+	for _, t := range texts {
+		if rule, ok := f.match(t.Value, t.Aloud); ok {
+			findings = append(findings, Finding{Field: t.Field, Category: rule.Category, Source: SourceRules, Term: rule.Term})
+		}
+	}
+	if f.Moderator == nil || len(texts) == 0 {
+		return findings
+	}
+	inputs := make([]string, len(texts))
+	for i, t := range texts {
+		inputs[i] = t.Value
+	}
+	flagged, err := f.Moderator.Moderate(ctx, inputs)
+	if err != nil {
+		log.Printf("[WARN] safety moderation failed, using local rules only: %v", err)
+		return findings
+	}
+	for i, categories := range flagged {
+		if i >= len(texts) {
+			break
+		}
+		for _, c := range categories {
+			if !texts[i].Aloud && !strings.HasPrefix(c, "sexual") {
+				continue
+			}
+			findings = append(findings, Finding{Field: texts[i].Field, Category: c, Source: SourceModeration})
+		}
+	}
+	return findings
+}
+
+// match returns the first rule the text breaks; AloudOnly rules are skipped
+// unless the text is read aloud.
+func (f *Filter) match(text string, aloud bool) (Rule, bool) {
+	if strings.TrimSpace(text) == "" {
+		return Rule{}, false
+	}
+	flat := compact(text)
+	for _, r := range f.Rules {
+		if r.AloudOnly && !aloud {
+			continue
+		}
+		if r.re != nil {
+			if r.re.MatchString(text) || r.re.MatchString(flat) {
+				return r, true
+			}
+			continue
+		}
+		if strings.Contains(flat, r.Term) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// compact lowercases s and drops whitespace so "笨 蛋" matches "笨蛋".
+func compact(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
@@ -0,0 +1,117 @@
+package safety
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRulesParse(t *testing.T) {
+	rules, err := Default()
+	if err != nil {
+		t.Fatalf("default rules: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, r := range rules {
+		seen[r.Category] = true
+	}
+	for _, c := range []string{CategoryViolence, CategoryAdult, CategoryInsult} {
+		if !seen[c] {
+			t.Fatalf("default rules miss category %s", c)
+		}
+	}
+}
+
+func TestParseRulesRejectsBadLines(t *testing.T) {
+	if _, err := ParseRules(strings.NewReader("insult\n")); err == nil {
+		t.Fatalf("expected error for missing term")
+	}
+	if _, err := ParseRules(strings.NewReader("insult re:(\n")); err == nil {
+		t.Fatalf("expected error for bad pattern")
+	}
+	if _, err := ParseRules(strings.NewReader("scope some\n")); err == nil {
+		t.Fatalf("expected error for unknown scope")
+	}
+	rules, err := ParseRules(strings.NewReader("scope aloud\ninsult 笨蛋\nscope all\nadult 色情\n"))
+	if err != nil || len(rules) != 2 || !rules[0].AloudOnly || rules[1].AloudOnly {
+		t.Fatalf("unexpected scoped rules %+v, %v", rules, err)
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	rules, _ := Default()
+	f := &Filter{Rules: rules}
+	cases := []struct {
+		text     string
+		category string
+	}{
+		{"你怎么这么笨，这都不会", CategoryInsult},
+		{"真是个笨 蛋", CategoryInsult},
+		{"That is a STUPID mistake", CategoryInsult},
+		{"再错就打你一顿", CategoryViolence},
+		{"我们把24拆成20和4，分别乘15。", ""},
+		{"这篇课文讲的是小英雄雨来", ""},
+		{"Sussex is a county", ""},
+	}
+	for _, tc := range cases {
+		got := f.Check(context.Background(), []Text{{Field: "explain_to_child", Value: tc.text, Aloud: true}})
+		if tc.category == "" {
+			if len(got) != 0 {
+				t.Fatalf("%q: unexpected findings %+v", tc.text, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0].Category != tc.category || got[0].Source != SourceRules {
+			t.Fatalf("%q: want %s finding, got %+v", tc.text, tc.category, got)
+		}
+	}
+}
+
+func TestCheckReadingPassage(t *testing.T) {
+	rules, _ := Default()
+	f := &Filter{Rules: rules}
+	passage := []Text{
+		{Field: "question_text", Value: "阅读短文：武松打死了老虎，狼被猎人杀死了。"},
+		{Field: "chinese.passage_summary", Value: "课文讲了傻子种树的故事，最后大家都夸他。"},
+		{Field: "chinese.key_sentences[0]", Value: "“你真笨！”狐狸笑着说。"},
+	}
+	if got := f.Check(context.Background(), passage); len(got) != 0 {
+		t.Fatalf("reading passage blocked: %+v", got)
+	}
+	if got := f.Check(context.Background(), []Text{{Field: "chinese.passage_summary", Value: "这是一个色情故事"}}); len(got) != 1 || got[0].Category != CategoryAdult {
+		t.Fatalf("adult rules must check every field, got %+v", got)
+	}
+	if got := f.Check(context.Background(), []Text{{Field: "explain_to_child", Value: "老虎被打死了", Aloud: true}}); len(got) != 1 {
+		t.Fatalf("violence read aloud must be found, got %+v", got)
+	}
+}
+
+type stubModerator struct {
+	flagged [][]string
+	err     error
+}
+
+func (m stubModerator) Moderate(ctx context.Context, inputs []string) ([][]string, error) {
+	return m.flagged, m.err
+}
+
+func TestCheckModeration(t *testing.T) {
+	texts := []Text{{Field: "a", Value: "ok", Aloud: true}, {Field: "b", Value: "also ok", Aloud: true}}
+	f := &Filter{Moderator: stubModerator{flagged: [][]string{nil, {"harassment"}}}}
+	got := f.Check(context.Background(), texts)
+	if len(got) != 1 || got[0].Field != "b" || got[0].Category != "harassment" || got[0].Source != SourceModeration {
+		t.Fatalf("unexpected findings %+v", got)
+	}
+
+	quoted := []Text{{Field: "question_text", Value: "story"}}
+	f.Moderator = stubModerator{flagged: [][]string{{"violence", "sexual"}}}
+	if got := f.Check(context.Background(), quoted); len(got) != 1 || got[0].Category != "sexual" {
+		t.Fatalf("text not read aloud keeps only sexual categories, got %+v", got)
+	}
+
+	f.Moderator = stubModerator{err: errors.New("down")}
+	if got := f.Check(context.Background(), texts); len(got) != 0 {
+		t.Fatalf("moderation errors should not block, got %+v", got)
+	}
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Child is a kid profile under a family. Profiles belong to the device that created
+// them; UserID is reserved for user accounts, which the API does not have yet, and
+// is never set.
+type Child struct {
+	ID         int64     `json:"id"`
+	UserID     *int64    `json:"-"`
+	DeviceID   string    `json:"-"`
+	Name       string    `json:"name"`
+	Grade      string    `json:"grade"`
+	SchoolYear string    `json:"schoolYear"`
+	Edition    string    `json:"edition"`
+	Region     string    `json:"region"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ChildInput holds the editable fields of a child profile.
+type ChildInput struct {
+	Name       string
+	Grade      string
+	SchoolYear string
+	Edition    string
+	Region     string
+}
+
+const childColumns = `id, user_id, device_id, name, grade, school_year, edition, region, created_at, updated_at`
+
+func scanChild(row pgx.Row) (Child, error) {
+	var ch Child
+	if err := row.Scan(&ch.ID, &ch.UserID, &ch.DeviceID, &ch.Name, &ch.Grade, &ch.SchoolYear, &ch.Edition, &ch.Region, &ch.CreatedAt, &ch.UpdatedAt); err != nil {
+		return Child{}, err
+	}
+	return ch, nil
+}
+
+func (s *Store) CreateChild(ctx context.Context, deviceID string, in ChildInput) (Child, error) {
+	q := `
+INSERT INTO children (device_id, name, grade, school_year, edition, region)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING ` + childColumns
+
+	return scanChild(s.DB.QueryRow(ctx, q, deviceID, in.Name, in.Grade, in.SchoolYear, in.Edition, in.Region))
+}
+
+func (s *Store) ListChildren(ctx context.Context, deviceID string) ([]Child, error) {
+	q := `
+SELECT ` + childColumns + `
+FROM children
+WHERE device_id = $1
+ORDER BY created_at ASC`
+
+	rows, err := s.DB.Query(ctx, q, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	children := make([]Child, 0, 4)
+	for rows.Next() {
+		ch, err := scanChild(rows)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, ch)
+	}
+	return children, rows.Err()
+}
+
+func (s *Store) GetChild(ctx context.Context, id int64, deviceID string) (Child, error) {
+	q := `
+SELECT ` + childColumns + `
+FROM children
+WHERE id = $1 AND device_id = $2`
+
+	return scanChild(s.DB.QueryRow(ctx, q, id, deviceID))
+}
+
+func (s *Store) UpdateChild(ctx context.Context, id int64, deviceID string, in ChildInput) (Child, error) {
+	q := `
+UPDATE children
+SET name = $3, grade = $4, school_year = $5, edition = $6, region = $7, updated_at = now()
+WHERE id = $1 AND device_id = $2
+RETURNING ` + childColumns
+
+	return scanChild(s.DB.QueryRow(ctx, q, id, deviceID, in.Name, in.Grade, in.SchoolYear, in.Edition, in.Region))
+}
+
+// DeleteChild removes a profile; its records stay in history without a child.
+func (s *Store) DeleteChild(ctx context.Context, id int64, deviceID string) error {
+	tag, err := s.DB.Exec(ctx, `DELETE FROM children WHERE id = $1 AND device_id = $2`, id, deviceID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
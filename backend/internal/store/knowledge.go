@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Statuses of a queued unknown knowledge point.
+const (
+	UnknownPending  = "pending"
+	UnknownResolved = "resolved"
+	UnknownRejected = "rejected"
+)
+
+// KnowledgePoint is a canonical knowledge point with the aliases that map to it.
+type KnowledgePoint struct {
+	ID        int64     `json:"id"`
+	Subject   string    `json:"subject"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UnknownKnowledgePoint is a model-produced name that matched no canonical point.
+type UnknownKnowledgePoint struct {
+	ID          int64     `json:"id"`
+	Subject     string    `json:"subject"`
+	Name        string    `json:"name"`
+	Occurrences int       `json:"occurrences"`
+	Status      string    `json:"status"`
+	PointID     *int64    `json:"pointId,omitempty"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+}
+
+func (s *Store) ListKnowledgePoints(ctx context.Context) ([]KnowledgePoint, error) {
+	const q = `
+SELECT p.id, p.subject, p.name, COALESCE(array_agg(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '{}'), p.created_at
+FROM knowledge_points p
+LEFT JOIN knowledge_point_aliases a ON a.point_id = p.id
+GROUP BY p.id
+ORDER BY p.subject, p.name`
+
+	rows, err := s.DB.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]KnowledgePoint, 0, 64)
+	for rows.Next() {
+		var p KnowledgePoint
+		if err := rows.Scan(&p.ID, &p.Subject, &p.Name, &p.Aliases, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// CreateKnowledgePoint adds a canonical point, or returns the existing one with the
+// same subject and name, and attaches the aliases to it.
+func (s *Store) CreateKnowledgePoint(ctx context.Context, subject, name string, aliases []string) (int64, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+INSERT INTO knowledge_points (subject, name) VALUES ($1, $2)
+ON CONFLICT (subject, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id`, subject, name).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if err := addAliases(ctx, tx, id, aliases); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// AddKnowledgeAliases points the aliases at a canonical point, moving any alias
+// that pointed elsewhere.
+func (s *Store) AddKnowledgeAliases(ctx context.Context, pointID int64, aliases []string) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM knowledge_points WHERE id = $1)`, pointID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return pgx.ErrNoRows
+	}
+	if err := addAliases(ctx, tx, pointID, aliases); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func addAliases(ctx context.Context, tx pgx.Tx, pointID int64, aliases []string) error {
+	for _, a := range aliases {
+		if a == "" {
+			continue
+		}
+		_, err := tx.Exec(ctx, `
+INSERT INTO knowledge_point_aliases (alias, point_id) VALUES ($1, $2)
+ON CONFLICT (alias) DO UPDATE SET point_id = EXCLUDED.point_id`, a, pointID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueueUnknownKnowledgePoints records names the taxonomy could not map, counting
+// repeats. Names an admin already rejected stay rejected.
+func (s *Store) QueueUnknownKnowledgePoints(ctx context.Context, subject string, names []string) error {
+	const q = `
+INSERT INTO knowledge_point_unknowns (subject, name)
+VALUES ($1, $2)
+ON CONFLICT (subject, name) DO UPDATE
+SET occurrences = knowledge_point_unknowns.occurrences + 1, last_seen_at = now()`
+
+	batch := &pgx.Batch{}
+	for _, n := range names {
+		batch.Queue(q, subject, n)
+	}
+	return s.DB.SendBatch(ctx, batch).Close()
+}
+
+func (s *Store) ListUnknownKnowledgePoints(ctx context.Context, status string, limit int) ([]UnknownKnowledgePoint, error) {
+	const q = `
+SELECT id, subject, name, occurrences, status, point_id, first_seen_at, last_seen_at
+FROM knowledge_point_unknowns
+WHERE status = $1
+ORDER BY occurrences DESC, last_seen_at DESC
+LIMIT $2`
+
+	rows, err := s.DB.Query(ctx, q, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]UnknownKnowledgePoint, 0, limit)
+	for rows.Next() {
+		var u UnknownKnowledgePoint
+		if err := rows.Scan(&u.ID, &u.Subject, &u.Name, &u.Occurrences, &u.Status, &u.PointID, &u.FirstSeenAt, &u.LastSeenAt); err != nil {
+			return nil, err
+		}
+		items = append(items, u)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) GetUnknownKnowledgePoint(ctx context.Context, id int64) (UnknownKnowledgePoint, error) {
+	const q = `
+SELECT id, subject, name, occurrences, status, point_id, first_seen_at, last_seen_at
+FROM knowledge_point_unknowns
+WHERE id = $1`
+
+	var u UnknownKnowledgePoint
+	err := s.DB.QueryRow(ctx, q, id).Scan(&u.ID, &u.Subject, &u.Name, &u.Occurrences, &u.Status, &u.PointID, &u.FirstSeenAt, &u.LastSeenAt)
+	if err != nil {
+		return UnknownKnowledgePoint{}, err
+	}
+	return u, nil
+}
+
+// SetUnknownKnowledgePointStatus closes a queue entry, linking the point it was
+// mapped to when resolved.
+func (s *Store) SetUnknownKnowledgePointStatus(ctx context.Context, id int64, status string, pointID *int64) error {
+	tag, err := s.DB.Exec(ctx, `UPDATE knowledge_point_unknowns SET status = $2, point_id = $3 WHERE id = $1`, id, status, pointID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
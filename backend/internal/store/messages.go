@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Message roles in a follow-up thread.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+type HomeworkMessage struct {
+	ID        int64     `json:"id"`
+	RecordID  int64     `json:"recordId"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (s *Store) ListHomeworkMessages(ctx context.Context, recordID int64) ([]HomeworkMessage, error) {
+	const q = `
+SELECT id, record_id, role, content, created_at
+FROM homework_messages
+WHERE record_id = $1
+ORDER BY id ASC`
+
+	rows, err := s.DB.Query(ctx, q, recordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]HomeworkMessage, 0, 8)
+	for rows.Next() {
+		var m HomeworkMessage
+		if err := rows.Scan(&m.ID, &m.RecordID, &m.Role, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	return items, rows.Err()
+}
+
+// AppendHomeworkExchange stores a parent question together with the reply, so a
+// failed model call never leaves an unanswered message in the thread.
+func (s *Store) AppendHomeworkExchange(ctx context.Context, recordID int64, question, reply string) error {
+	const q = `
+INSERT INTO homework_messages (record_id, role, content, created_at)
+VALUES ($1, 'user', $2, now()), ($1, 'assistant', $3, now())`
+
+	_, err := s.DB.Exec(ctx, q, recordID, question, reply)
+	return err
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReviewIntervals are the spaced-repetition gaps in days; passing the last stage
+// marks a mistake as mastered.
+var ReviewIntervals = []int{1, 2, 4, 7, 15, 30}
+
+// NextReview advances the review stage and picks the next review day.
+func NextReview(stage int, remembered bool, day time.Time) (int, time.Time, bool) {
+	if !remembered {
+		return 0, day.AddDate(0, 0, ReviewIntervals[0]), false
+	}
+	stage++
+	if stage >= len(ReviewIntervals) {
+		return len(ReviewIntervals), day.AddDate(0, 0, ReviewIntervals[len(ReviewIntervals)-1]), true
+	}
+	return stage, day.AddDate(0, 0, ReviewIntervals[stage]), false
+}
+
+// Mistake is a record the family put in the wrong-question notebook (错题本).
+type Mistake struct {
+	ID              int64      `json:"id"`
+	RecordID        int64      `json:"recordId"`
+	DeviceID        string     `json:"-"`
+	Title           string     `json:"title"`
+	QuestionText    string     `json:"questionText"`
+	WrongAnswer     string     `json:"wrongAnswer"`
+	Cause           string     `json:"cause"`
+	Note            string     `json:"note"`
+	Subject         string     `json:"subject"`
+	KnowledgePoints []string   `json:"knowledgePoints"`
+	ReviewStage     int        `json:"reviewStage"`
+	NextReviewOn    time.Time  `json:"nextReviewOn"`
+	LastReviewedAt  *time.Time `json:"lastReviewedAt,omitempty"`
+	MasteredAt      *time.Time `json:"masteredAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+// UpsertMistake is the input for marking a record as a mistake.
+type UpsertMistake struct {
+	RecordID        int64
+	DeviceID        string
+	WrongAnswer     string
+	Cause           string
+	Note            string
+	Subject         string
+	KnowledgePoints []string
+	NextReviewOn    time.Time
+}
+
+// MistakeFilter narrows ListMistakes; empty fields match everything.
+type MistakeFilter struct {
+	Subject        string
+	KnowledgePoint string
+	Cause          string
+	DueOn          *time.Time
+}
+
+const mistakeColumns = `m.id, m.record_id, m.device_id, r.title, COALESCE(r.question_text, ''), m.wrong_answer, m.cause, m.note, m.subject, m.knowledge_points, m.review_stage, m.next_review_on, m.last_reviewed_at, m.mastered_at, m.created_at`
+
+func scanMistake(row pgx.Row) (Mistake, error) {
+	var m Mistake
+	err := row.Scan(&m.ID, &m.RecordID, &m.DeviceID, &m.Title, &m.QuestionText, &m.WrongAnswer, &m.Cause, &m.Note, &m.Subject,
+		&m.KnowledgePoints, &m.ReviewStage, &m.NextReviewOn, &m.LastReviewedAt, &m.MasteredAt, &m.CreatedAt)
+	if err != nil {
+		return Mistake{}, err
+	}
+	return m, nil
+}
+
+// UpsertMistake marks a record as a mistake. Marking it again updates the details
+// and restarts the review schedule.
+func (s *Store) UpsertMistake(ctx context.Context, in UpsertMistake) (Mistake, error) {
+	const q = `
+INSERT INTO mistakes (record_id, device_id, wrong_answer, cause, note, subject, knowledge_points, next_review_on)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (record_id) DO UPDATE
+SET wrong_answer = EXCLUDED.wrong_answer, cause = EXCLUDED.cause, note = EXCLUDED.note, subject = EXCLUDED.subject,
+    knowledge_points = EXCLUDED.knowledge_points, review_stage = 0, next_review_on = EXCLUDED.next_review_on,
+    mastered_at = NULL, updated_at = now()
+RETURNING id`
+
+	var id int64
+	kps := in.KnowledgePoints
+	if kps == nil {
+		kps = []string{}
+	}
+	if err := s.DB.QueryRow(ctx, q, in.RecordID, in.DeviceID, in.WrongAnswer, in.Cause, in.Note, in.Subject, kps, in.NextReviewOn).Scan(&id); err != nil {
+		return Mistake{}, err
+	}
+	return s.GetMistake(ctx, id, in.DeviceID)
+}
+
+func (s *Store) GetMistake(ctx context.Context, id int64, deviceID string) (Mistake, error) {
+	q := `
+SELECT ` + mistakeColumns + `
+FROM mistakes m JOIN homework_records r ON r.id = m.record_id
+WHERE m.id = $1 AND m.device_id = $2`
+	return scanMistake(s.DB.QueryRow(ctx, q, id, deviceID))
+}
+
+func (s *Store) GetMistakeByRecord(ctx context.Context, recordID int64, deviceID string) (Mistake, error) {
+	q := `
+SELECT ` + mistakeColumns + `
+FROM mistakes m JOIN homework_records r ON r.id = m.record_id
+WHERE m.record_id = $1 AND m.device_id = $2`
+	return scanMistake(s.DB.QueryRow(ctx, q, recordID, deviceID))
+}
+
+func (s *Store) DeleteMistakeByRecord(ctx context.Context, recordID int64, deviceID string) error {
+	tag, err := s.DB.Exec(ctx, `DELETE FROM mistakes WHERE record_id = $1 AND device_id = $2`, recordID, deviceID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ListMistakes returns the notebook of a device. With DueOn set, only unmastered
+// mistakes scheduled on or before that day are returned, most overdue first.
+func (s *Store) ListMistakes(ctx context.Context, deviceID string, f MistakeFilter, limit int) ([]Mistake, error) {
+	q := `
+SELECT ` + mistakeColumns + `
+FROM mistakes m JOIN homework_records r ON r.id = m.record_id
+WHERE m.device_id = $1
+  AND ($2 = '' OR m.subject = $2)
+  AND ($3 = '' OR $3 = ANY(m.knowledge_points))
+  AND ($4 = '' OR m.cause = $4)
+  AND ($5::date IS NULL OR (m.next_review_on <= $5::date AND m.mastered_at IS NULL))
+ORDER BY CASE WHEN $5::date IS NULL THEN m.created_at END DESC, m.next_review_on ASC
+LIMIT $6`
+
+	rows, err := s.DB.Query(ctx, q, deviceID, f.Subject, f.KnowledgePoint, f.Cause, f.DueOn, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]Mistake, 0, 16)
+	for rows.Next() {
+		m, err := scanMistake(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	return items, rows.Err()
+}
+
+// UpdateMistakeReview stores the outcome of a review: the new stage, the next review
+// day and whether the mistake is now mastered.
+func (s *Store) UpdateMistakeReview(ctx context.Context, id int64, deviceID string, stage int, next time.Time, mastered bool) (Mistake, error) {
+	const q = `
+UPDATE mistakes
+SET review_stage = $3, next_review_on = $4, last_reviewed_at = now(),
+    mastered_at = CASE WHEN $5 THEN COALESCE(mastered_at, now()) ELSE NULL END, updated_at = now()
+WHERE id = $1 AND device_id = $2`
+	tag, err := s.DB.Exec(ctx, q, id, deviceID, stage, next, mastered)
+	if err != nil {
+		return Mistake{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return Mistake{}, pgx.ErrNoRows
+	}
+	return s.GetMistake(ctx, id, deviceID)
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextReview(t *testing.T) {
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		stage      int
+		remembered bool
+		wantStage  int
+		wantDays   int
+		mastered   bool
+	}{
+		{0, true, 1, 2, false},
+		{1, true, 2, 4, false},
+		{3, true, 4, 15, false},
+		{4, true, 5, 30, false},
+		{5, true, 6, 30, true},
+		{6, true, 6, 30, true},
+		{3, false, 0, 1, false},
+		{5, false, 0, 1, false},
+	}
+	for _, tc := range cases {
+		stage, next, mastered := NextReview(tc.stage, tc.remembered, day)
+		if stage != tc.wantStage || !next.Equal(day.AddDate(0, 0, tc.wantDays)) || mastered != tc.mastered {
+			t.Fatalf("NextReview(%d, %v) = %d, %s, %v; want %d, +%dd, %v",
+				tc.stage, tc.remembered, stage, next.Format("2006-01-02"), mastered, tc.wantStage, tc.wantDays, tc.mastered)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PracticeProblem is a variant problem generated from an analyzed record. Answer and
+// Hint are kept out of JSON so handlers decide when to reveal them.
+type PracticeProblem struct {
+	ID          int64      `json:"id"`
+	RecordID    int64      `json:"recordId"`
+	Position    int        `json:"position"`
+	Question    string     `json:"question"`
+	Answer      string     `json:"-"`
+	Hint        string     `json:"-"`
+	ChildAnswer string     `json:"childAnswer"`
+	IsCorrect   *bool      `json:"isCorrect"`
+	Attempts    int        `json:"attempts"`
+	AnsweredAt  *time.Time `json:"answeredAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// NewPracticeProblem is one generated problem to store.
+type NewPracticeProblem struct {
+	Question string
+	Answer   string
+	Hint     string
+}
+
+const practiceColumns = `id, record_id, position, question, answer, hint, child_answer, is_correct, attempts, answered_at, created_at`
+
+func scanPractice(row pgx.Row) (PracticeProblem, error) {
+	var p PracticeProblem
+	err := row.Scan(&p.ID, &p.RecordID, &p.Position, &p.Question, &p.Answer, &p.Hint, &p.ChildAnswer, &p.IsCorrect, &p.Attempts, &p.AnsweredAt, &p.CreatedAt)
+	if err != nil {
+		return PracticeProblem{}, err
+	}
+	return p, nil
+}
+
+// CreatePracticeProblems appends a generated batch after any earlier problems of the record.
+func (s *Store) CreatePracticeProblems(ctx context.Context, recordID int64, problems []NewPracticeProblem) ([]PracticeProblem, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var start int
+	const qpos = `SELECT COALESCE(MAX(position), 0) FROM practice_problems WHERE record_id = $1`
+	if err := tx.QueryRow(ctx, qpos, recordID).Scan(&start); err != nil {
+		return nil, err
+	}
+
+	q := `
+INSERT INTO practice_problems (record_id, position, question, answer, hint)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING ` + practiceColumns
+	out := make([]PracticeProblem, 0, len(problems))
+	for i, p := range problems {
+		created, err := scanPractice(tx.QueryRow(ctx, q, recordID, start+i+1, p.Question, p.Answer, p.Hint))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, created)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *Store) ListPracticeProblems(ctx context.Context, recordID int64) ([]PracticeProblem, error) {
+	q := `
+SELECT ` + practiceColumns + `
+FROM practice_problems
+WHERE record_id = $1
+ORDER BY position ASC`
+	rows, err := s.DB.Query(ctx, q, recordID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]PracticeProblem, 0, 5)
+	for rows.Next() {
+		p, err := scanPractice(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, p)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) GetPracticeProblem(ctx context.Context, id, recordID int64) (PracticeProblem, error) {
+	q := `
+SELECT ` + practiceColumns + `
+FROM practice_problems
+WHERE id = $1 AND record_id = $2`
+	return scanPractice(s.DB.QueryRow(ctx, q, id, recordID))
+}
+
+// RecordPracticeAnswer stores the child's latest attempt.
+func (s *Store) RecordPracticeAnswer(ctx context.Context, id int64, childAnswer string, correct bool) (PracticeProblem, error) {
+	q := `
+UPDATE practice_problems
+SET child_answer = $2, is_correct = $3, attempts = attempts + 1, answered_at = now()
+WHERE id = $1
+RETURNING ` + practiceColumns
+	return scanPractice(s.DB.QueryRow(ctx, q, id, childAnswer, correct))
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// DevicePreferences are per-device settings. A device without a row gets the zero
+// value, i.e. server defaults.
+type DevicePreferences struct {
+	Language  string    `json:"language"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (s *Store) GetDevicePreferences(ctx context.Context, deviceID string) (DevicePreferences, error) {
+	var p DevicePreferences
+	err := s.DB.QueryRow(ctx, `SELECT language, updated_at FROM device_preferences WHERE device_id = $1`, deviceID).
+		Scan(&p.Language, &p.UpdatedAt)
+	if IsNotFound(err) {
+		return DevicePreferences{}, nil
+	}
+	return p, err
+}
+
+func (s *Store) SetDeviceLanguage(ctx context.Context, deviceID, language string) (DevicePreferences, error) {
+	const q = `
+INSERT INTO device_preferences (device_id, language)
+VALUES ($1, $2)
+ON CONFLICT (device_id) DO UPDATE SET language = EXCLUDED.language, updated_at = now()
+RETURNING language, updated_at`
+
+	var p DevicePreferences
+	err := s.DB.QueryRow(ctx, q, deviceID, language).Scan(&p.Language, &p.UpdatedAt)
+	return p, err
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Rehearsal session states and turn roles. The server speaks as the child.
+const (
+	RehearsalActive   = "active"
+	RehearsalFinished = "finished"
+
+	RoleParent = "parent"
+	RoleChild  = "child"
+)
+
+type RehearsalTurn struct {
+	ID        int64     `json:"id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type RehearsalSession struct {
+	ID         int64           `json:"id"`
+	RecordID   int64           `json:"recordId"`
+	DeviceID   string          `json:"-"`
+	Status     string          `json:"status"`
+	Score      json.RawMessage `json:"score,omitempty"`
+	Turns      []RehearsalTurn `json:"turns"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// CreateRehearsal starts a session with the child's opening line.
+func (s *Store) CreateRehearsal(ctx context.Context, recordID int64, deviceID, opening string) (RehearsalSession, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return RehearsalSession{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	const q = `INSERT INTO rehearsal_sessions (record_id, device_id) VALUES ($1, $2) RETURNING id`
+	if err := tx.QueryRow(ctx, q, recordID, deviceID).Scan(&id); err != nil {
+		return RehearsalSession{}, err
+	}
+	const qt = `INSERT INTO rehearsal_turns (session_id, role, content) VALUES ($1, 'child', $2)`
+	if _, err := tx.Exec(ctx, qt, id, opening); err != nil {
+		return RehearsalSession{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return RehearsalSession{}, err
+	}
+	return s.GetRehearsal(ctx, id, recordID, deviceID)
+}
+
+func (s *Store) GetRehearsal(ctx context.Context, id, recordID int64, deviceID string) (RehearsalSession, error) {
+	const q = `
+SELECT id, record_id, device_id, status, score_json, created_at, finished_at
+FROM rehearsal_sessions
+WHERE id = $1 AND record_id = $2 AND device_id = $3`
+
+	var sess RehearsalSession
+	err := s.DB.QueryRow(ctx, q, id, recordID, deviceID).Scan(
+		&sess.ID, &sess.RecordID, &sess.DeviceID, &sess.Status, &sess.Score, &sess.CreatedAt, &sess.FinishedAt,
+	)
+	if err != nil {
+		return RehearsalSession{}, err
+	}
+
+	const qt = `
+SELECT id, role, content, created_at
+FROM rehearsal_turns
+WHERE session_id = $1
+ORDER BY id ASC`
+	rows, err := s.DB.Query(ctx, qt, id)
+	if err != nil {
+		return RehearsalSession{}, err
+	}
+	defer rows.Close()
+	sess.Turns = make([]RehearsalTurn, 0, 8)
+	for rows.Next() {
+		var t RehearsalTurn
+		if err := rows.Scan(&t.ID, &t.Role, &t.Content, &t.CreatedAt); err != nil {
+			return RehearsalSession{}, err
+		}
+		sess.Turns = append(sess.Turns, t)
+	}
+	return sess, rows.Err()
+}
+
+// AppendRehearsalTurns stores the parent's line and the child's reply together.
+func (s *Store) AppendRehearsalTurns(ctx context.Context, sessionID int64, parent, child string) error {
+	const q = `
+INSERT INTO rehearsal_turns (session_id, role, content)
+VALUES ($1, 'parent', $2), ($1, 'child', $3)`
+	_, err := s.DB.Exec(ctx, q, sessionID, parent, child)
+	return err
+}
+
+// FinishRehearsal stores the score of an active session and closes it.
+func (s *Store) FinishRehearsal(ctx context.Context, id int64, deviceID string, score any) error {
+	b, err := json.Marshal(score)
+	if err != nil {
+		return err
+	}
+	const q = `
+UPDATE rehearsal_sessions
+SET status = 'finished', score_json = $3, finished_at = now()
+WHERE id = $1 AND device_id = $2 AND status = 'active'`
+	tag, err := s.DB.Exec(ctx, q, id, deviceID, b)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReportRecord is the slice of a homework record the weekly report aggregates.
+type ReportRecord struct {
+	Subject  string
+	SolvedAt time.Time
+	Result   json.RawMessage
+}
+
+// WeeklyReport is a stored weekly summary. ChildID is nil for a device-wide report.
+type WeeklyReport struct {
+	ID        int64           `json:"id"`
+	DeviceID  string          `json:"-"`
+	ChildID   *int64          `json:"childId,omitempty"`
+	WeekStart time.Time       `json:"weekStart"`
+	Stats     json.RawMessage `json:"stats"`
+	Narrative string          `json:"narrative"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+const weeklyReportColumns = `id, device_id, child_id, week_start, stats, narrative, created_at, updated_at`
+
+// ListReportRecords returns the analyzed records solved in [from, to). Page records
+// hold no analysis of their own and are left out. A positive childID keeps only that
+// child's records.
+func (s *Store) ListReportRecords(ctx context.Context, deviceID string, childID int64, from, to time.Time) ([]ReportRecord, error) {
+	const q = `
+SELECT subject, solved_at, result_json
+FROM homework_records
+WHERE device_id = $1 AND ($2::bigint = 0 OR child_id = $2) AND solved_at >= $3 AND solved_at < $4 AND kind <> 'page'
+ORDER BY solved_at ASC`
+
+	rows, err := s.DB.Query(ctx, q, deviceID, childID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]ReportRecord, 0, 32)
+	for rows.Next() {
+		var r ReportRecord
+		if err := rows.Scan(&r.Subject, &r.SolvedAt, &r.Result); err != nil {
+			return nil, err
+		}
+		items = append(items, r)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) CountReportRecords(ctx context.Context, deviceID string, childID int64, from, to time.Time) (int, error) {
+	const q = `
+SELECT count(*)
+FROM homework_records
+WHERE device_id = $1 AND ($2::bigint = 0 OR child_id = $2) AND solved_at >= $3 AND solved_at < $4 AND kind <> 'page'`
+
+	var n int
+	err := s.DB.QueryRow(ctx, q, deviceID, childID, from, to).Scan(&n)
+	return n, err
+}
+
+// CountMistakesCreated counts records put in the wrong-question notebook in [from, to).
+func (s *Store) CountMistakesCreated(ctx context.Context, deviceID string, childID int64, from, to time.Time) (int, error) {
+	const q = `
+SELECT count(*)
+FROM mistakes m
+JOIN homework_records r ON r.id = m.record_id
+WHERE m.device_id = $1 AND ($2::bigint = 0 OR r.child_id = $2) AND m.created_at >= $3 AND m.created_at < $4`
+
+	var n int
+	err := s.DB.QueryRow(ctx, q, deviceID, childID, from, to).Scan(&n)
+	return n, err
+}
+
+func (s *Store) GetWeeklyReport(ctx context.Context, deviceID string, childID int64, weekStart time.Time) (WeeklyReport, error) {
+	q := `
+SELECT ` + weeklyReportColumns + `
+FROM weekly_reports
+WHERE device_id = $1 AND COALESCE(child_id, 0) = $2 AND week_start = $3`
+
+	return scanWeeklyReport(s.DB.QueryRow(ctx, q, deviceID, childID, weekStart))
+}
+
+func (s *Store) UpsertWeeklyReport(ctx context.Context, deviceID string, childID int64, weekStart time.Time, stats any, narrative string) (WeeklyReport, error) {
+	statsBytes, err := json.Marshal(stats)
+	if err != nil {
+		return WeeklyReport{}, err
+	}
+	var child *int64
+	if childID > 0 {
+		child = &childID
+	}
+	q := `
+INSERT INTO weekly_reports (device_id, child_id, week_start, stats, narrative)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (device_id, (COALESCE(child_id, 0)), week_start) DO UPDATE
+SET stats = EXCLUDED.stats, narrative = EXCLUDED.narrative, updated_at = now()
+RETURNING ` + weeklyReportColumns
+
+	return scanWeeklyReport(s.DB.QueryRow(ctx, q, deviceID, child, weekStart, statsBytes, narrative))
+}
+
+func scanWeeklyReport(row pgx.Row) (WeeklyReport, error) {
+	var r WeeklyReport
+	if err := row.Scan(&r.ID, &r.DeviceID, &r.ChildID, &r.WeekStart, &r.Stats, &r.Narrative, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return WeeklyReport{}, err
+	}
+	return r, nil
+}
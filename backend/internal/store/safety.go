@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Actions taken on an unsafe analysis result.
+const (
+	SafetyRegenerated = "regenerated"
+	SafetyBlocked     = "blocked"
+)
+
+// SafetyIncident is an analysis result that failed the safety check.
+type SafetyIncident struct {
+	ID        int64           `json:"id"`
+	DeviceID  string          `json:"deviceId"`
+	Action    string          `json:"action"`
+	Findings  json.RawMessage `json:"findings"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+func (s *Store) CreateSafetyIncident(ctx context.Context, deviceID, action string, findings any) error {
+	b, err := json.Marshal(findings)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec(ctx, `INSERT INTO safety_incidents (device_id, action, findings) VALUES ($1, $2, $3)`, deviceID, action, b)
+	return err
+}
+
+func (s *Store) ListSafetyIncidents(ctx context.Context, limit int) ([]SafetyIncident, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	rows, err := s.DB.Query(ctx, `
+SELECT id, device_id, action, findings, created_at
+FROM safety_incidents
+ORDER BY created_at DESC
+LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]SafetyIncident, 0, limit)
+	for rows.Next() {
+		var it SafetyIncident
+		if err := rows.Scan(&it.ID, &it.DeviceID, &it.Action, &it.Findings, &it.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
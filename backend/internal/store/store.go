@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 	"time"
 
@@ -38,6 +39,10 @@ type HistoryItem struct {
 	Mode         string    `json:"mode"`
 	SolvedAt     time.Time `json:"solvedAt"`
 	QuestionText string    `json:"questionText"`
+	Kind         string    `json:"kind"`
+	ParentID     *int64    `json:"parentId,omitempty"`
+	ChildID      *int64    `json:"childId,omitempty"`
+	Subject      string    `json:"subject"`
 }
 
 type HomeworkRecord struct {
@@ -51,9 +56,85 @@ type HomeworkRecord struct {
 	Summary       string          `json:"summary"`
 	QuestionText  string          `json:"questionText"`
 	ResultJSONRaw json.RawMessage `json:"result"`
-	SolvedAt      time.Time       `json:"solvedAt"`
-	CreatedAt     time.Time       `json:"createdAt"`
-	UpdatedAt     time.Time       `json:"updatedAt"`
+	Kind          string          `json:"kind"`
+	ParentID      *int64          `json:"parentId,omitempty"`
+	ChildID       *int64          `json:"childId,omitempty"`
+	Subject       string          `json:"subject"`
+	PageQuestions json.RawMessage `json:"pageQuestions"`
+	Region        json.RawMessage `json:"region,omitempty"`
+	Audio         json.RawMessage `json:"audio,omitempty"`
+	// RecognizedQuestionText is the model's reading of a question the parent has
+	// since corrected; nil while uncorrected.
+	RecognizedQuestionText *string `json:"recognizedQuestionText,omitempty"`
+	// CurrentVersionID is the result version the record shows; nil for pages.
+	CurrentVersionID *int64    `json:"currentVersionId,omitempty"`
+	SolvedAt         time.Time `json:"solvedAt"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Record kinds: a single photographed question, a whole worksheet page, one
+// question analyzed out of a page, a region cropped from another record's photo,
+// a spoken question whose source is the recording, or a typed question with no source.
+const (
+	KindSingle   = "single"
+	KindPage     = "page"
+	KindQuestion = "question"
+	KindCrop     = "crop"
+	KindVoice    = "voice"
+	KindText     = "text"
+)
+
+// NewHomework is the input for CreateHomework. PageQuestions and Region are
+// marshaled to JSON when set.
+type NewHomework struct {
+	DeviceID      string
+	Mode          string
+	Kind          string
+	ParentID      int64
+	ChildID       int64
+	Subject       string
+	ImageURL      string
+	QuestionText  string
+	Grade         string
+	Result        any
+	PageQuestions any
+	Region        any
+	// Meta describes what produced Result; every kind but a page stores Result
+	// as the record's first version.
+	Meta ResultMeta
+}
+
+const homeworkColumns = `id, device_id, mode, title, grade, COALESCE(thumb_url, ''), COALESCE(source_image_url, ''), COALESCE(summary, ''), COALESCE(question_text, ''), result_json, kind, parent_id, child_id, subject, page_questions, region_json, audio_json, recognized_question_text, current_version_id, solved_at, created_at, updated_at`
+
+const homeworkSelect = `SELECT ` + homeworkColumns + ` FROM homework_records WHERE id = $1`
+
+const historyColumns = `id, title, grade, COALESCE(thumb_url, ''), COALESCE(summary, ''), mode, solved_at, COALESCE(question_text, ''), kind, parent_id, child_id, subject`
+
+func scanHomework(row pgx.Row) (HomeworkRecord, error) {
+	var rec HomeworkRecord
+	err := row.Scan(
+		&rec.ID, &rec.DeviceID, &rec.Mode, &rec.Title, &rec.Grade, &rec.ThumbURL, &rec.SourceImage,
+		&rec.Summary, &rec.QuestionText, &rec.ResultJSONRaw, &rec.Kind, &rec.ParentID, &rec.ChildID, &rec.Subject, &rec.PageQuestions, &rec.Region, &rec.Audio, &rec.RecognizedQuestionText, &rec.CurrentVersionID,
+		&rec.SolvedAt, &rec.CreatedAt, &rec.UpdatedAt,
+	)
+	if err != nil {
+		return HomeworkRecord{}, err
+	}
+	return rec, nil
+}
+
+func scanHistoryItems(rows pgx.Rows, capacity int) ([]HistoryItem, error) {
+	defer rows.Close()
+	items := make([]HistoryItem, 0, capacity)
+	for rows.Next() {
+		var it HistoryItem
+		if err := rows.Scan(&it.ID, &it.Title, &it.Grade, &it.ThumbURL, &it.Summary, &it.Mode, &it.SolvedAt, &it.QuestionText, &it.Kind, &it.ParentID, &it.ChildID, &it.Subject); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
 }
 
 func Connect(ctx context.Context, dbURL string) (*pgxpool.Pool, error) {
@@ -141,73 +222,117 @@ RETURNING id, openid, COALESCE(unionid, ''), nick_name, avatar_url, COALESCE(pho
 	return u, nil
 }
 
-func (s *Store) ListHistoryByDevice(ctx context.Context, deviceID string, limit int) ([]HistoryItem, error) {
-	const q = `
-SELECT id, title, grade, COALESCE(thumb_url, ''), COALESCE(summary, ''), mode, solved_at, COALESCE(question_text, '')
+// ListHistoryByDevice returns the newest records of a device. A positive childID
+// keeps only that child's records.
+func (s *Store) ListHistoryByDevice(ctx context.Context, deviceID string, childID int64, limit int) ([]HistoryItem, error) {
+	q := `
+SELECT ` + historyColumns + `
 FROM homework_records
-WHERE device_id = $1
+WHERE device_id = $1 AND ($2::bigint = 0 OR child_id = $2)
 ORDER BY solved_at DESC
-LIMIT $2`
+LIMIT $3`
 
-	rows, err := s.DB.Query(ctx, q, deviceID, limit)
+	rows, err := s.DB.Query(ctx, q, deviceID, childID, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanHistoryItems(rows, limit)
+}
 
-	items := make([]HistoryItem, 0, limit)
-	for rows.Next() {
-		var it HistoryItem
-		if err := rows.Scan(&it.ID, &it.Title, &it.Grade, &it.ThumbURL, &it.Summary, &it.Mode, &it.SolvedAt, &it.QuestionText); err != nil {
-			return nil, err
-		}
-		items = append(items, it)
+// ListChildHomework returns the questions already analyzed out of a page record.
+func (s *Store) ListChildHomework(ctx context.Context, parentID int64, deviceID string) ([]HistoryItem, error) {
+	q := `
+SELECT ` + historyColumns + `
+FROM homework_records
+WHERE parent_id = $1 AND device_id = $2
+ORDER BY created_at ASC`
+
+	rows, err := s.DB.Query(ctx, q, parentID, deviceID)
+	if err != nil {
+		return nil, err
 	}
-	return items, rows.Err()
+	return scanHistoryItems(rows, 8)
 }
 
 func (s *Store) GetHomeworkByIDAndDevice(ctx context.Context, id int64, deviceID string) (HomeworkRecord, error) {
-	const q = `
-SELECT id, device_id, mode, title, grade, COALESCE(thumb_url, ''), COALESCE(source_image_url, ''), COALESCE(summary, ''), COALESCE(question_text, ''), result_json, solved_at, created_at, updated_at
+	q := `
+SELECT ` + homeworkColumns + `
 FROM homework_records
 WHERE id = $1 AND device_id = $2`
 
-	var rec HomeworkRecord
-	err := s.DB.QueryRow(ctx, q, id, deviceID).Scan(
-		&rec.ID, &rec.DeviceID, &rec.Mode, &rec.Title, &rec.Grade, &rec.ThumbURL, &rec.SourceImage,
-		&rec.Summary, &rec.QuestionText, &rec.ResultJSONRaw, &rec.SolvedAt, &rec.CreatedAt, &rec.UpdatedAt,
-	)
+	return scanHomework(s.DB.QueryRow(ctx, q, id, deviceID))
+}
+
+func (s *Store) CreateHomework(ctx context.Context, in NewHomework) (HomeworkRecord, error) {
+	resultBytes, err := json.Marshal(in.Result)
 	if err != nil {
 		return HomeworkRecord{}, err
 	}
-	return rec, nil
-}
+	questionsBytes := []byte("[]")
+	if in.PageQuestions != nil {
+		if questionsBytes, err = json.Marshal(in.PageQuestions); err != nil {
+			return HomeworkRecord{}, err
+		}
+	}
+	var regionBytes []byte
+	if in.Region != nil {
+		if regionBytes, err = json.Marshal(in.Region); err != nil {
+			return HomeworkRecord{}, err
+		}
+	}
+	kind := in.Kind
+	if kind == "" {
+		kind = KindSingle
+	}
+	var parentID, childID *int64
+	if in.ParentID > 0 {
+		parentID = &in.ParentID
+	}
+	if in.ChildID > 0 {
+		childID = &in.ChildID
+	}
+	title := buildTitle(in.QuestionText)
+	summary := buildSummary(in.QuestionText)
+	// A recording is no thumbnail.
+	thumbURL := in.ImageURL
+	if kind == KindVoice {
+		thumbURL = ""
+	}
 
-func (s *Store) CreateHomework(ctx context.Context, deviceID string, mode string, imageURL string, questionText string, grade string, resultJSON any) (HomeworkRecord, error) {
-	resultBytes, err := json.Marshal(resultJSON)
+	tx, err := s.DB.Begin(ctx)
 	if err != nil {
 		return HomeworkRecord{}, err
 	}
-	title := buildTitle(questionText)
-	summary := buildSummary(questionText)
+	defer tx.Rollback(ctx)
 
 	const q = `
-INSERT INTO homework_records (device_id, mode, title, grade, thumb_url, source_image_url, summary, question_text, result_json, solved_at)
-VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,now())
-RETURNING id, device_id, mode, title, grade, COALESCE(thumb_url, ''), COALESCE(source_image_url, ''), COALESCE(summary, ''), COALESCE(question_text, ''), result_json, solved_at, created_at, updated_at`
-
-	var rec HomeworkRecord
-	err = s.DB.QueryRow(ctx, q, deviceID, mode, title, grade, imageURL, imageURL, summary, questionText, resultBytes).Scan(
-		&rec.ID, &rec.DeviceID, &rec.Mode, &rec.Title, &rec.Grade, &rec.ThumbURL, &rec.SourceImage,
-		&rec.Summary, &rec.QuestionText, &rec.ResultJSONRaw, &rec.SolvedAt, &rec.CreatedAt, &rec.UpdatedAt,
-	)
+INSERT INTO homework_records (device_id, mode, title, grade, thumb_url, source_image_url, summary, question_text, result_json, kind, parent_id, child_id, subject, page_questions, region_json, solved_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,now())
+RETURNING id`
+	var id int64
+	if err := tx.QueryRow(ctx, q, in.DeviceID, in.Mode, title, in.Grade, thumbURL, in.ImageURL, summary, in.QuestionText,
+		resultBytes, kind, parentID, childID, in.Subject, questionsBytes, regionBytes).Scan(&id); err != nil {
+		return HomeworkRecord{}, err
+	}
+	// A page only lists its questions; it has no result to version.
+	if kind != KindPage {
+		v := newVersion{Mode: in.Mode, QuestionText: in.QuestionText, Grade: in.Grade, Subject: in.Subject, Result: resultBytes, Meta: in.Meta}
+		if err := setCurrentVersion(ctx, tx, id, v); err != nil {
+			return HomeworkRecord{}, err
+		}
+	}
+	rec, err := scanHomework(tx.QueryRow(ctx, homeworkSelect, id))
 	if err != nil {
 		return HomeworkRecord{}, err
 	}
-	return rec, nil
+	return rec, tx.Commit(ctx)
 }
 
-func (s *Store) UpdateHomeworkResult(ctx context.Context, id int64, deviceID string, mode string, questionText string, grade string, resultJSON any) (HomeworkRecord, error) {
+// UpdateHomeworkResult stores a new result as a new version and makes it current.
+// Earlier versions are kept and can be selected again. corrected marks
+// questionText as the parent's correction, keeping the recognized text the way
+// CorrectQuestionText does.
+func (s *Store) UpdateHomeworkResult(ctx context.Context, id int64, deviceID string, mode string, questionText string, corrected bool, grade string, subject string, resultJSON any, meta ResultMeta) (HomeworkRecord, error) {
 	resultBytes, err := json.Marshal(resultJSON)
 	if err != nil {
 		return HomeworkRecord{}, err
@@ -215,21 +340,139 @@ func (s *Store) UpdateHomeworkResult(ctx context.Context, id int64, deviceID str
 	title := buildTitle(questionText)
 	summary := buildSummary(questionText)
 
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return HomeworkRecord{}, err
+	}
+	defer tx.Rollback(ctx)
+
 	const q = `
 UPDATE homework_records
-SET mode=$3, title=$4, grade=$5, summary=$6, question_text=$7, result_json=$8, solved_at=now(), updated_at=now()
+SET mode=$3, title=$4, grade=$5, summary=$6, question_text=$7, result_json=$8, subject=$9, audio_json=NULL, solved_at=now(), updated_at=now(),
+    recognized_question_text = CASE WHEN $10 THEN COALESCE(recognized_question_text, question_text, '') ELSE recognized_question_text END,
+    question_corrected_at = CASE WHEN $10 THEN now() ELSE question_corrected_at END
+WHERE id = $1 AND device_id = $2`
+	tag, err := tx.Exec(ctx, q, id, deviceID, mode, title, grade, summary, questionText, resultBytes, subject, corrected)
+	if err != nil {
+		return HomeworkRecord{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return HomeworkRecord{}, pgx.ErrNoRows
+	}
+	v := newVersion{Mode: mode, QuestionText: questionText, Grade: grade, Subject: subject, Result: resultBytes, Meta: meta}
+	if err := setCurrentVersion(ctx, tx, id, v); err != nil {
+		return HomeworkRecord{}, err
+	}
+	rec, err := scanHomework(tx.QueryRow(ctx, homeworkSelect, id))
+	if err != nil {
+		return HomeworkRecord{}, err
+	}
+	return rec, tx.Commit(ctx)
+}
+
+// CorrectQuestionText replaces a record's question text with the parent's
+// correction. The first recognized text is kept; later corrections only change
+// question_text.
+func (s *Store) CorrectQuestionText(ctx context.Context, id int64, deviceID, text string) (HomeworkRecord, error) {
+	q := `
+UPDATE homework_records
+SET recognized_question_text = COALESCE(recognized_question_text, question_text, ''), question_corrected_at = now(),
+    question_text = $3, title = $4, summary = $5, updated_at = now()
 WHERE id = $1 AND device_id = $2
-RETURNING id, device_id, mode, title, grade, COALESCE(thumb_url, ''), COALESCE(source_image_url, ''), COALESCE(summary, ''), COALESCE(question_text, ''), result_json, solved_at, created_at, updated_at`
+RETURNING ` + homeworkColumns
 
-	var rec HomeworkRecord
-	err = s.DB.QueryRow(ctx, q, id, deviceID, mode, title, grade, summary, questionText, resultBytes).Scan(
-		&rec.ID, &rec.DeviceID, &rec.Mode, &rec.Title, &rec.Grade, &rec.ThumbURL, &rec.SourceImage,
-		&rec.Summary, &rec.QuestionText, &rec.ResultJSONRaw, &rec.SolvedAt, &rec.CreatedAt, &rec.UpdatedAt,
-	)
+	return scanHomework(s.DB.QueryRow(ctx, q, id, deviceID, text, buildTitle(text), buildSummary(text)))
+}
+
+// QuestionCorrection pairs a recognized question text with the parent's fix.
+type QuestionCorrection struct {
+	RecordID    int64     `json:"recordId"`
+	Kind        string    `json:"kind"`
+	Subject     string    `json:"subject"`
+	Recognized  string    `json:"recognized"`
+	Corrected   string    `json:"corrected"`
+	CorrectedAt time.Time `json:"correctedAt"`
+}
+
+func (s *Store) ListQuestionCorrections(ctx context.Context, limit int) ([]QuestionCorrection, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	const q = `
+SELECT id, kind, subject, recognized_question_text, COALESCE(question_text, ''), question_corrected_at
+FROM homework_records
+WHERE question_corrected_at IS NOT NULL
+ORDER BY question_corrected_at DESC
+LIMIT $1`
+
+	rows, err := s.DB.Query(ctx, q, limit)
 	if err != nil {
-		return HomeworkRecord{}, err
+		return nil, err
 	}
-	return rec, nil
+	defer rows.Close()
+
+	items := make([]QuestionCorrection, 0, limit)
+	for rows.Next() {
+		var it QuestionCorrection
+		if err := rows.Scan(&it.RecordID, &it.Kind, &it.Subject, &it.Recognized, &it.Corrected, &it.CorrectedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// SetHomeworkAudio stores the speech audio URLs of a record's result.
+func (s *Store) SetHomeworkAudio(ctx context.Context, id int64, deviceID string, audio any) error {
+	b, err := json.Marshal(audio)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec(ctx, `UPDATE homework_records SET audio_json = $3, updated_at = now() WHERE id = $1 AND device_id = $2`, id, deviceID, b)
+	return err
+}
+
+// ReferencedUploads returns the file names under the upload dir that records still
+// point at.
+func (s *Store) ReferencedUploads(ctx context.Context) (map[string]bool, error) {
+	const q = `
+SELECT COALESCE(source_image_url, '') FROM homework_records WHERE COALESCE(source_image_url, '') <> ''
+UNION
+SELECT COALESCE(thumb_url, '') FROM homework_records WHERE COALESCE(thumb_url, '') <> ''
+UNION
+SELECT blob_url FROM upload_sessions WHERE blob_url <> '' AND expires_at > now()`
+
+	rows, err := s.DB.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := make(map[string]bool)
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		refs[path.Base(u)] = true
+	}
+	return refs, rows.Err()
+}
+
+// ExpireRecordImages detaches photos from records created before the cutoff while
+// keeping their text results. The files themselves are removed by the orphan sweep
+// once nothing references them.
+func (s *Store) ExpireRecordImages(ctx context.Context, before time.Time) (int64, error) {
+	const q = `
+UPDATE homework_records
+SET source_image_url = '', thumb_url = '', image_purged_at = now(), updated_at = now()
+WHERE created_at < $1 AND source_image_url <> ''`
+
+	tag, err := s.DB.Exec(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
 
 func buildTitle(questionText string) string {
@@ -258,8 +501,8 @@ func buildSummary(questionText string) string {
 
 func (s *Store) ListHistory(ctx context.Context, userID int64, limit int) ([]HistoryItem, error) {
 	// Backward compatibility for old API; user_id history no longer used in mini-program flow.
-	const q = `
-SELECT id, title, grade, COALESCE(thumb_url, ''), COALESCE(summary, ''), mode, solved_at, COALESCE(question_text, '')
+	q := `
+SELECT ` + historyColumns + `
 FROM homework_records
 WHERE user_id = $1
 ORDER BY solved_at DESC
@@ -271,16 +514,7 @@ LIMIT $2`
 		}
 		return nil, err
 	}
-	defer rows.Close()
-	items := make([]HistoryItem, 0, limit)
-	for rows.Next() {
-		var it HistoryItem
-		if err := rows.Scan(&it.ID, &it.Title, &it.Grade, &it.ThumbURL, &it.Summary, &it.Mode, &it.SolvedAt, &it.QuestionText); err != nil {
-			return nil, err
-		}
-		items = append(items, it)
-	}
-	return items, rows.Err()
+	return scanHistoryItems(rows, limit)
 }
 
 func nullable(s string) any {
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Upload session states.
+const (
+	UploadPending   = "pending"
+	UploadCompleted = "completed"
+)
+
+// UploadSession tracks a chunked upload. Chunks must arrive in order, so
+// ReceivedSize is also the offset of the next expected chunk.
+type UploadSession struct {
+	ID           string    `json:"uploadId"`
+	DeviceID     string    `json:"-"`
+	TotalSize    int64     `json:"totalSize"`
+	ReceivedSize int64     `json:"offset"`
+	Status       string    `json:"status"`
+	BlobURL      string    `json:"imageUrl,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+const uploadSessionColumns = `id, device_id, total_size, received_size, status, blob_url, content_type, expires_at, created_at, updated_at`
+
+func scanUploadSession(row pgx.Row) (UploadSession, error) {
+	var u UploadSession
+	err := row.Scan(&u.ID, &u.DeviceID, &u.TotalSize, &u.ReceivedSize, &u.Status, &u.BlobURL, &u.ContentType, &u.ExpiresAt, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return UploadSession{}, err
+	}
+	return u, nil
+}
+
+func (s *Store) CreateUploadSession(ctx context.Context, id, deviceID string, totalSize int64, expiresAt time.Time) (UploadSession, error) {
+	q := `
+INSERT INTO upload_sessions (id, device_id, total_size, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING ` + uploadSessionColumns
+	return scanUploadSession(s.DB.QueryRow(ctx, q, id, deviceID, totalSize, expiresAt))
+}
+
+// GetUploadSession returns a session that has not expired yet.
+func (s *Store) GetUploadSession(ctx context.Context, id, deviceID string) (UploadSession, error) {
+	q := `
+SELECT ` + uploadSessionColumns + `
+FROM upload_sessions
+WHERE id = $1 AND device_id = $2 AND expires_at > now()`
+	return scanUploadSession(s.DB.QueryRow(ctx, q, id, deviceID))
+}
+
+// AdvanceUploadSession moves the received offset forward only if it still equals
+// from, so two racing writes for the same offset cannot both succeed.
+func (s *Store) AdvanceUploadSession(ctx context.Context, id, deviceID string, from, to int64) (UploadSession, error) {
+	q := `
+UPDATE upload_sessions
+SET received_size = $4, updated_at = now()
+WHERE id = $1 AND device_id = $2 AND status = 'pending' AND received_size = $3 AND expires_at > now()
+RETURNING ` + uploadSessionColumns
+	return scanUploadSession(s.DB.QueryRow(ctx, q, id, deviceID, from, to))
+}
+
+func (s *Store) CompleteUploadSession(ctx context.Context, id, deviceID, blobURL, contentType string) (UploadSession, error) {
+	q := `
+UPDATE upload_sessions
+SET status = 'completed', blob_url = $3, content_type = $4, updated_at = now()
+WHERE id = $1 AND device_id = $2 AND status = 'pending' AND received_size = total_size
+RETURNING ` + uploadSessionColumns
+	return scanUploadSession(s.DB.QueryRow(ctx, q, id, deviceID, blobURL, contentType))
+}
+
+// DeleteExpiredUploadSessions removes sessions past their expiry and returns their
+// ids so the partial files can be cleaned up.
+func (s *Store) DeleteExpiredUploadSessions(ctx context.Context, before time.Time) ([]string, error) {
+	const q = `DELETE FROM upload_sessions WHERE expires_at < $1 RETURNING id`
+	rows, err := s.DB.Query(ctx, q, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
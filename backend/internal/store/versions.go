@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ResultMeta records the model and prompt version that produced a result.
+type ResultMeta struct {
+	Model         string
+	PromptVersion string
+}
+
+// ResultVersion is one immutable generation of a record's analysis.
+type ResultVersion struct {
+	ID            int64           `json:"id"`
+	RecordID      int64           `json:"recordId"`
+	Mode          string          `json:"mode"`
+	Model         string          `json:"model"`
+	PromptVersion string          `json:"promptVersion"`
+	QuestionText  string          `json:"questionText"`
+	Grade         string          `json:"grade"`
+	Subject       string          `json:"subject"`
+	Result        json.RawMessage `json:"result"`
+	Current       bool            `json:"current"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+type newVersion struct {
+	Mode         string
+	QuestionText string
+	Grade        string
+	Subject      string
+	Result       []byte
+	Meta         ResultMeta
+}
+
+// setCurrentVersion appends a version to a record and points the record at it.
+func setCurrentVersion(ctx context.Context, tx pgx.Tx, recordID int64, v newVersion) error {
+	const q = `
+INSERT INTO homework_result_versions (homework_id, mode, model, prompt_version, question_text, grade, subject, result_json)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+RETURNING id`
+	var id int64
+	if err := tx.QueryRow(ctx, q, recordID, v.Mode, v.Meta.Model, v.Meta.PromptVersion, v.QuestionText, v.Grade, v.Subject, v.Result).Scan(&id); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `UPDATE homework_records SET current_version_id = $2 WHERE id = $1`, recordID, id)
+	return err
+}
+
+// ListResultVersions returns a record's versions, newest first.
+func (s *Store) ListResultVersions(ctx context.Context, recordID int64, deviceID string) ([]ResultVersion, error) {
+	const q = `
+SELECT v.id, v.homework_id, v.mode, v.model, v.prompt_version, v.question_text, v.grade, v.subject, v.result_json,
+       v.id = r.current_version_id, v.created_at
+FROM homework_result_versions v
+JOIN homework_records r ON r.id = v.homework_id
+WHERE v.homework_id = $1 AND r.device_id = $2
+ORDER BY v.id DESC`
+
+	rows, err := s.DB.Query(ctx, q, recordID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	versions := make([]ResultVersion, 0, 4)
+	for rows.Next() {
+		var v ResultVersion
+		var current *bool
+		if err := rows.Scan(&v.ID, &v.RecordID, &v.Mode, &v.Model, &v.PromptVersion, &v.QuestionText, &v.Grade, &v.Subject, &v.Result, &current, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		v.Current = current != nil && *current
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// SelectResultVersion makes an earlier version the record's shown result without
+// generating anything. See applyVersion for what is copied back.
+func (s *Store) SelectResultVersion(ctx context.Context, recordID int64, deviceID string, versionID int64) (HomeworkRecord, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return HomeworkRecord{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	rec, err := scanHomework(tx.QueryRow(ctx, homeworkSelect+` AND device_id = $2 FOR UPDATE`, recordID, deviceID))
+	if err != nil {
+		return HomeworkRecord{}, err
+	}
+	const qv = `
+SELECT mode, question_text, grade, subject, result_json
+FROM homework_result_versions
+WHERE id = $1 AND homework_id = $2`
+	var v newVersion
+	if err := tx.QueryRow(ctx, qv, versionID, recordID).Scan(&v.Mode, &v.QuestionText, &v.Grade, &v.Subject, &v.Result); err != nil {
+		return HomeworkRecord{}, err
+	}
+	rec = applyVersion(rec, versionID, v)
+
+	q := `
+UPDATE homework_records
+SET current_version_id = $2, mode = $3, title = $4, grade = $5, summary = $6, question_text = $7, result_json = $8, subject = $9,
+    audio_json = NULL, updated_at = now()
+WHERE id = $1
+RETURNING ` + homeworkColumns
+	rec, err = scanHomework(tx.QueryRow(ctx, q, rec.ID, versionID, rec.Mode, rec.Title, rec.Grade,
+		rec.Summary, rec.QuestionText, []byte(rec.ResultJSONRaw), rec.Subject))
+	if err != nil {
+		return HomeworkRecord{}, err
+	}
+	return rec, tx.Commit(ctx)
+}
+
+// applyVersion returns rec showing version v: its result, mode, grade and subject.
+// The question text comes from the version too, unless the parent has corrected
+// it; a correction outlives the versions generated before it, so selecting one of
+// them never brings the misread text back. solved_at keeps the time of the latest
+// generation, and speech audio is dropped because it was made from the old text.
+func applyVersion(rec HomeworkRecord, versionID int64, v newVersion) HomeworkRecord {
+	rec.CurrentVersionID = &versionID
+	rec.Mode, rec.Grade, rec.Subject, rec.ResultJSONRaw = v.Mode, v.Grade, v.Subject, v.Result
+	if rec.RecognizedQuestionText == nil {
+		rec.QuestionText = v.QuestionText
+	}
+	rec.Title, rec.Summary = buildTitle(rec.QuestionText), buildSummary(rec.QuestionText)
+	rec.Audio = nil
+	return rec
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyVersionCopiesResultColumns(t *testing.T) {
+	rec := HomeworkRecord{
+		ID: 1, Mode: "detailed", Grade: "四年级", Subject: "math", QuestionText: "25 × 4 = ?",
+		ResultJSONRaw: json.RawMessage(`{"v":2}`), Audio: json.RawMessage(`{"voice":"alloy"}`),
+	}
+	v := newVersion{Mode: "guided", QuestionText: "24 × 15 = ?", Grade: "三年级", Subject: "chinese", Result: []byte(`{"v":1}`)}
+
+	got := applyVersion(rec, 7, v)
+	if got.CurrentVersionID == nil || *got.CurrentVersionID != 7 {
+		t.Fatalf("current version = %v, want 7", got.CurrentVersionID)
+	}
+	if got.Mode != "guided" || got.Grade != "三年级" || got.Subject != "chinese" || string(got.ResultJSONRaw) != `{"v":1}` {
+		t.Fatalf("result columns not copied: %+v", got)
+	}
+	if got.QuestionText != v.QuestionText || got.Title != buildTitle(v.QuestionText) || got.Summary != buildSummary(v.QuestionText) {
+		t.Fatalf("question text = %q title = %q summary = %q", got.QuestionText, got.Title, got.Summary)
+	}
+	if got.Audio != nil {
+		t.Fatalf("audio kept: %s", got.Audio)
+	}
+}
+
+func TestApplyVersionKeepsCorrectedQuestion(t *testing.T) {
+	// The first generation misread 15 as 16; the parent corrected it and
+	// regenerated, then went back to the first version's explanation.
+	misread := "24 × 16 = ?"
+	corrected := "24 × 15 = ?"
+	first := newVersion{Mode: "guided", QuestionText: misread, Result: []byte(`{"v":1}`)}
+	rec := HomeworkRecord{
+		ID: 1, Mode: "guided", QuestionText: corrected, RecognizedQuestionText: &misread,
+		Title: buildTitle(corrected), Summary: buildSummary(corrected), ResultJSONRaw: json.RawMessage(`{"v":2}`),
+	}
+
+	got := applyVersion(rec, 1, first)
+	if got.QuestionText != corrected || got.Title != buildTitle(corrected) || got.Summary != buildSummary(corrected) {
+		t.Fatalf("question text = %q, want the correction %q", got.QuestionText, corrected)
+	}
+	if got.RecognizedQuestionText == nil || *got.RecognizedQuestionText != misread {
+		t.Fatalf("recognized text = %v, want %q", got.RecognizedQuestionText, misread)
+	}
+	if string(got.ResultJSONRaw) != `{"v":1}` {
+		t.Fatalf("result = %s, want the first version", got.ResultJSONRaw)
+	}
+}
@@ -0,0 +1,131 @@
+// Package taxonomy maps the free-form knowledge point names the model produces to
+// canonical knowledge points, so "分配律" and "乘法分配律" count as the same thing.
+package taxonomy
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+// Store is the subset of store.Store the taxonomy needs.
+type Store interface {
+	ListKnowledgePoints(ctx context.Context) ([]store.KnowledgePoint, error)
+	QueueUnknownKnowledgePoints(ctx context.Context, subject string, names []string) error
+}
+
+type point struct {
+	id      int64
+	name    string
+	subject string
+}
+
+// Taxonomy is an in-memory snapshot of the canonical points and their aliases.
+// Reload it after the points change.
+type Taxonomy struct {
+	Store Store
+
+	mu    sync.RWMutex
+	byKey map[string][]point
+}
+
+// Reload replaces the snapshot with the points currently in the store.
+func (t *Taxonomy) Reload(ctx context.Context) error {
+	points, err := t.Store.ListKnowledgePoints(ctx)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string][]point, len(points)*2)
+	for _, p := range points {
+		pt := point{id: p.ID, name: p.Name, subject: p.Subject}
+		for _, name := range append([]string{p.Name}, p.Aliases...) {
+			if k := Key(name); k != "" {
+				byKey[k] = append(byKey[k], pt)
+			}
+		}
+	}
+	t.mu.Lock()
+	t.byKey = byKey
+	t.mu.Unlock()
+	return nil
+}
+
+// NormalizeKnowledge replaces names with their canonical points, dropping
+// duplicates. Names with no match are kept as written and queued for review; ids
+// holds the canonical id of each returned name, or 0 for unmatched ones.
+func (t *Taxonomy) NormalizeKnowledge(subject string, names []string) ([]string, []int64) {
+	t.mu.RLock()
+	byKey := t.byKey
+	t.mu.RUnlock()
+
+	out := make([]string, 0, len(names))
+	ids := make([]int64, 0, len(names))
+	seen := map[string]bool{}
+	var unknown []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		k := Key(name)
+		if k == "" {
+			continue
+		}
+		var id int64
+		if p, ok := match(byKey[k], subject); ok {
+			name, id = p.name, p.id
+		} else {
+			unknown = append(unknown, name)
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+		ids = append(ids, id)
+	}
+	if len(unknown) > 0 && t.Store != nil {
+		go t.queue(subject, unknown)
+	}
+	return out, ids
+}
+
+func (t *Taxonomy) queue(subject string, names []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := t.Store.QueueUnknownKnowledgePoints(ctx, subject, names); err != nil {
+		log.Printf("[WARN] queue unknown knowledge points: %v", err)
+	}
+}
+
+// match prefers a point of the same subject; a point of another subject only
+// matches when the subject is unknown and the name is unambiguous.
+func match(candidates []point, subject string) (point, bool) {
+	for _, p := range candidates {
+		if p.subject == subject {
+			return p, true
+		}
+	}
+	if (subject == "" || subject == "other") && len(candidates) == 1 {
+		return candidates[0], true
+	}
+	return point{}, false
+}
+
+// Key folds a name for matching: full-width letters and digits become half-width,
+// letters are lowercased and spaces and punctuation are dropped.
+func Key(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r >= '！' && r <= '～' {
+			r -= '！' - '!'
+		}
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package taxonomy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"whatsdot-aibuddy/backend/internal/store"
+)
+
+type fakeStore struct {
+	points []store.KnowledgePoint
+	queued chan []string
+}
+
+func (f *fakeStore) ListKnowledgePoints(ctx context.Context) ([]store.KnowledgePoint, error) {
+	return f.points, nil
+}
+
+func (f *fakeStore) QueueUnknownKnowledgePoints(ctx context.Context, subject string, names []string) error {
+	f.queued <- names
+	return nil
+}
+
+func TestNormalizeKnowledgeMapsAliasesAndQueuesUnknown(t *testing.T) {
+	fs := &fakeStore{
+		points: []store.KnowledgePoint{
+			{ID: 1, Subject: "math", Name: "乘法分配律", Aliases: []string{"分配律"}},
+			{ID: 2, Subject: "english", Name: "一般过去时", Aliases: []string{"Simple Past"}},
+		},
+		queued: make(chan []string, 1),
+	}
+	tx := &Taxonomy{Store: fs}
+	if err := tx.Reload(context.Background()); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	names, ids := tx.NormalizeKnowledge("math", []string{"分配律", " 乘法分配律 ", "两位数 乘法", ""})
+	if !reflect.DeepEqual(names, []string{"乘法分配律", "两位数 乘法"}) || !reflect.DeepEqual(ids, []int64{1, 0}) {
+		t.Fatalf("unexpected normalization: %v %v", names, ids)
+	}
+	if got := <-fs.queued; !reflect.DeepEqual(got, []string{"两位数 乘法"}) {
+		t.Fatalf("expected unknown point to be queued, got %v", got)
+	}
+
+	names, ids = tx.NormalizeKnowledge("other", []string{"simple-past"})
+	if !reflect.DeepEqual(names, []string{"一般过去时"}) || ids[0] != 2 {
+		t.Fatalf("expected unambiguous match for unknown subject, got %v %v", names, ids)
+	}
+}
+
+func TestKeyFoldsWidthCaseAndPunctuation(t *testing.T) {
+	if got := Key(" Ｐａｓｔ-Simple（一般）"); got != "pastsimple一般" {
+		t.Fatalf("unexpected key: %q", got)
+	}
+}
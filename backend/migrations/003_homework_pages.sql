@@ -0,0 +1,8 @@
+ALTER TABLE homework_records
+  ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'single',
+  ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES homework_records(id) ON DELETE CASCADE,
+  ADD COLUMN IF NOT EXISTS page_questions JSONB NOT NULL DEFAULT '[]'::jsonb,
+  ADD COLUMN IF NOT EXISTS region_json JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_homework_records_parent_id
+  ON homework_records(parent_id, created_at);
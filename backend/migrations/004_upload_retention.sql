@@ -0,0 +1,6 @@
+ALTER TABLE homework_records
+  ADD COLUMN IF NOT EXISTS image_purged_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_homework_records_created_at_with_image
+  ON homework_records(created_at)
+  WHERE source_image_url <> '';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS homework_messages (
+  id BIGSERIAL PRIMARY KEY,
+  record_id BIGINT NOT NULL REFERENCES homework_records(id) ON DELETE CASCADE,
+  role TEXT NOT NULL,
+  content TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_homework_messages_record_id
+  ON homework_messages(record_id, id);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS rehearsal_sessions (
+  id BIGSERIAL PRIMARY KEY,
+  record_id BIGINT NOT NULL REFERENCES homework_records(id) ON DELETE CASCADE,
+  device_id TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'active',
+  score_json JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_rehearsal_sessions_record_id
+  ON rehearsal_sessions(record_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS rehearsal_turns (
+  id BIGSERIAL PRIMARY KEY,
+  session_id BIGINT NOT NULL REFERENCES rehearsal_sessions(id) ON DELETE CASCADE,
+  role TEXT NOT NULL,
+  content TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rehearsal_turns_session_id
+  ON rehearsal_turns(session_id, id);
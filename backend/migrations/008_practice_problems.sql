@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS practice_problems (
+  id BIGSERIAL PRIMARY KEY,
+  record_id BIGINT NOT NULL REFERENCES homework_records(id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  question TEXT NOT NULL,
+  answer TEXT NOT NULL,
+  hint TEXT NOT NULL DEFAULT '',
+  child_answer TEXT NOT NULL DEFAULT '',
+  is_correct BOOLEAN,
+  attempts INT NOT NULL DEFAULT 0,
+  answered_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_practice_problems_record_id
+  ON practice_problems(record_id, id);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS mistakes (
+  id BIGSERIAL PRIMARY KEY,
+  record_id BIGINT NOT NULL UNIQUE REFERENCES homework_records(id) ON DELETE CASCADE,
+  device_id TEXT NOT NULL,
+  wrong_answer TEXT NOT NULL DEFAULT '',
+  cause TEXT NOT NULL DEFAULT 'other',
+  note TEXT NOT NULL DEFAULT '',
+  subject TEXT NOT NULL DEFAULT '',
+  knowledge_points TEXT[] NOT NULL DEFAULT '{}',
+  review_stage INT NOT NULL DEFAULT 0,
+  next_review_on DATE NOT NULL,
+  last_reviewed_at TIMESTAMPTZ,
+  mastered_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mistakes_device_id_next_review_on
+  ON mistakes(device_id, next_review_on);
+
+CREATE INDEX IF NOT EXISTS idx_mistakes_knowledge_points
+  ON mistakes USING GIN (knowledge_points);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS children (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+  device_id TEXT NOT NULL DEFAULT '',
+  name TEXT NOT NULL,
+  grade TEXT NOT NULL DEFAULT '',
+  school_year TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_children_device_id
+  ON children(device_id);
+
+CREATE INDEX IF NOT EXISTS idx_children_user_id
+  ON children(user_id);
+
+ALTER TABLE homework_records
+  ADD COLUMN IF NOT EXISTS child_id BIGINT REFERENCES children(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_homework_records_device_child_solved_at
+  ON homework_records(device_id, child_id, solved_at DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE children
+  ADD COLUMN IF NOT EXISTS edition TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS region TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+ALTER TABLE homework_records
+  ADD COLUMN IF NOT EXISTS subject TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_homework_records_device_id_subject
+  ON homework_records(device_id, subject);
@@ -0,0 +1,84 @@
+CREATE TABLE IF NOT EXISTS knowledge_points (
+  id BIGSERIAL PRIMARY KEY,
+  subject TEXT NOT NULL DEFAULT '',
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (subject, name)
+);
+
+CREATE TABLE IF NOT EXISTS knowledge_point_aliases (
+  alias TEXT PRIMARY KEY,
+  point_id BIGINT NOT NULL REFERENCES knowledge_points(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_point_aliases_point_id
+  ON knowledge_point_aliases(point_id);
+
+-- Model output that matched no canonical point, waiting for an admin to map it to a
+-- point, promote it to a new point, or reject it.
+CREATE TABLE IF NOT EXISTS knowledge_point_unknowns (
+  id BIGSERIAL PRIMARY KEY,
+  subject TEXT NOT NULL DEFAULT '',
+  name TEXT NOT NULL,
+  occurrences INT NOT NULL DEFAULT 1,
+  status TEXT NOT NULL DEFAULT 'pending',
+  point_id BIGINT REFERENCES knowledge_points(id) ON DELETE SET NULL,
+  first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (subject, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_point_unknowns_status
+  ON knowledge_point_unknowns(status, occurrences DESC);
+
+INSERT INTO knowledge_points (subject, name) VALUES
+  ('math', '乘法分配律'),
+  ('math', '乘法结合律'),
+  ('math', '乘法交换律'),
+  ('math', '加法结合律'),
+  ('math', '加法交换律'),
+  ('math', '两位数乘两位数'),
+  ('math', '表内乘法'),
+  ('math', '有余数的除法'),
+  ('math', '分数的初步认识'),
+  ('math', '小数的初步认识'),
+  ('math', '估算'),
+  ('math', '平均数'),
+  ('math', '鸡兔同笼'),
+  ('math', '植树问题'),
+  ('chinese', '比喻句'),
+  ('chinese', '拟人句'),
+  ('chinese', '多音字'),
+  ('chinese', '关联词'),
+  ('english', '一般过去时'),
+  ('english', '现在进行时')
+ON CONFLICT (subject, name) DO NOTHING;
+
+INSERT INTO knowledge_point_aliases (alias, point_id)
+SELECT a.alias, p.id
+FROM (VALUES
+  ('math', '乘法分配律', '分配律'),
+  ('math', '乘法分配律', '乘法的分配律'),
+  ('math', '乘法结合律', '乘法的结合律'),
+  ('math', '乘法交换律', '乘法的交换律'),
+  ('math', '加法结合律', '加法的结合律'),
+  ('math', '加法交换律', '加法的交换律'),
+  ('math', '两位数乘两位数', '两位数乘法'),
+  ('math', '两位数乘两位数', '两位数乘两位数的乘法'),
+  ('math', '表内乘法', '乘法口诀'),
+  ('math', '有余数的除法', '有余数除法'),
+  ('math', '分数的初步认识', '认识分数'),
+  ('math', '小数的初步认识', '认识小数'),
+  ('math', '估算', '口算与估算'),
+  ('math', '平均数', '求平均数'),
+  ('math', '鸡兔同笼', '鸡兔同笼问题'),
+  ('chinese', '比喻句', '比喻'),
+  ('chinese', '拟人句', '拟人'),
+  ('chinese', '关联词', '关联词语'),
+  ('english', '一般过去时', 'simple past'),
+  ('english', '一般过去时', 'past simple'),
+  ('english', '现在进行时', 'present continuous')
+) AS a(subject, name, alias)
+JOIN knowledge_points p ON p.subject = a.subject AND p.name = a.name
+ON CONFLICT (alias) DO NOTHING;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS weekly_reports (
+  id BIGSERIAL PRIMARY KEY,
+  device_id TEXT NOT NULL,
+  child_id BIGINT REFERENCES children(id) ON DELETE CASCADE,
+  week_start DATE NOT NULL,
+  stats JSONB NOT NULL DEFAULT '{}'::jsonb,
+  narrative TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One report per device, child (0 for the whole device) and week.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_weekly_reports_device_child_week
+  ON weekly_reports(device_id, (COALESCE(child_id, 0)), week_start);
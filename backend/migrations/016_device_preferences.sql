@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS device_preferences (
+  device_id TEXT PRIMARY KEY,
+  language TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
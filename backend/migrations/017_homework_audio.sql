@@ -0,0 +1,2 @@
+-- Speech audio of a record's child-facing text; cleared whenever the result changes.
+ALTER TABLE homework_records ADD COLUMN IF NOT EXISTS audio_json JSONB;
@@ -0,0 +1,7 @@
+-- The model's original recognition of a question the parent corrected; NULL while
+-- uncorrected. Kept to measure recognition quality.
+ALTER TABLE homework_records ADD COLUMN IF NOT EXISTS recognized_question_text TEXT;
+ALTER TABLE homework_records ADD COLUMN IF NOT EXISTS question_corrected_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_homework_records_question_corrected_at
+  ON homework_records(question_corrected_at DESC) WHERE question_corrected_at IS NOT NULL;
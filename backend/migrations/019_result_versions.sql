@@ -0,0 +1,33 @@
+-- Every analysis result a record has had; rows are never updated. The record's
+-- result columns mirror the version selected by current_version_id.
+CREATE TABLE IF NOT EXISTS homework_result_versions (
+  id BIGSERIAL PRIMARY KEY,
+  homework_id BIGINT NOT NULL REFERENCES homework_records(id) ON DELETE CASCADE,
+  mode TEXT NOT NULL,
+  model TEXT NOT NULL DEFAULT '',
+  prompt_version TEXT NOT NULL DEFAULT '',
+  question_text TEXT NOT NULL DEFAULT '',
+  grade TEXT NOT NULL DEFAULT '',
+  subject TEXT NOT NULL DEFAULT '',
+  result_json JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_homework_result_versions_homework_id
+  ON homework_result_versions(homework_id, id DESC);
+
+ALTER TABLE homework_records ADD COLUMN IF NOT EXISTS current_version_id BIGINT
+  REFERENCES homework_result_versions(id) ON DELETE SET NULL;
+
+-- Existing results become each record's first version; model and prompt version
+-- were not tracked before.
+INSERT INTO homework_result_versions (homework_id, mode, question_text, grade, subject, result_json, created_at)
+SELECT id, mode, COALESCE(question_text, ''), grade, subject, result_json, solved_at
+FROM homework_records r
+WHERE kind <> 'page' AND current_version_id IS NULL
+  AND NOT EXISTS (SELECT 1 FROM homework_result_versions v WHERE v.homework_id = r.id);
+
+UPDATE homework_records r
+SET current_version_id = v.id
+FROM homework_result_versions v
+WHERE v.homework_id = r.id AND r.current_version_id IS NULL;